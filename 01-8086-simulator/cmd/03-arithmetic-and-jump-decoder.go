@@ -0,0 +1,1111 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ Decoding MOV, the arithmetic group (ADD/SUB/CMP), the logic group
+ (AND/OR/XOR/TEST/NOT/NEG), the shift/rotate group, INC/DEC/PUSH/POP,
+ unconditional JMP/CALL/RET, the string primitives (MOVS/CMPS/SCAS/
+ LODS/STOS, with an optional REP prefix) and conditional jumps.
+
+ Assembly:
+  ADD ax, bx    // ax <- ax + bx
+  SUB cx, 12    // cx <- cx - 12
+  CMP bx, [bp]  // set flags from bx - [bp]
+  AND ax, bx    // ax <- ax & bx
+  OR  cx, 12    // cx <- cx | 12
+  XOR bx, [bp]  // bx <- bx ^ [bp]
+  TEST ax, bx   // set flags from ax & bx, discard the result
+  NOT cx        // cx <- ^cx
+  NEG cx        // cx <- -cx
+  SHL ax, 1     // ax <- ax << 1
+  SHR ax, cl    // ax <- ax >> cl (count taken from cl)
+  INC cx        // cx <- cx + 1
+  PUSH bp       // push bp onto the stack
+  JMP label_0   // unconditional jump
+  CALL label_1  // push the return address, jump
+  RET           // pop the return address, jump to it
+  JNZ label_0   // jump if the last result was non-zero
+  MOVSB         // move one byte from [si] to [di], advance si/di
+  REP MOVSW     // repeat MOVSW while cx != 0
+
+ Machine code (reg/mem <-> reg, same shape as MOV):
+ byte 1            byte 2
+ |x|x|x|x|x|x|x|x| |x|x|x|x|x|x|x|x|
+ |Opcode     |D|W| |MOD|REG  |R/M  |
+
+ Machine code (immediate to reg/mem, REG field picks the operation):
+ byte 1            byte 2            byte 3 (+4)
+ |1|0|0|0|0|0|S|W| |MOD|OP |R/M  |   data[, data if w=1]
+
+ OP in REG field: 000 = ADD, 001 = OR, 100 = AND, 101 = SUB, 110 = XOR, 111 = CMP
+
+ Machine code (immediate to accumulator):
+ byte 1            byte 2 (+3)
+ |0|0|o|o|o|1|0|w| data[, data if w=1]    OOO: same codes as the REG field above
+ (ADD=0000010w, OR=0000110w, AND=0010010w, SUB=0010110w, XOR=0011010w, CMP=0011110w, TEST=1010100w)
+
+ Machine code (TEST reg/mem and register - same shape as the reg/mem<->reg
+ group above but with no D bit, since the result is discarded either way):
+ byte 1            byte 2
+ |1|0|0|0|0|1|0|w| |MOD|REG  |R/M  |
+
+ Machine code (group 3: TEST/NOT/NEG reg/mem, REG field picks the
+ operation; TEST also reads an immediate the same way the arithmetic
+ group does, NOT/NEG take no further operand):
+ byte 1            byte 2            byte 3 (+4, TEST only)
+ |1|1|1|1|0|1|1|w| |MOD|OP |R/M  |   data[, data if w=1]
+ OP in REG field: 000 = TEST, 010 = NOT, 011 = NEG (100-111 are the
+ MUL/IMUL/DIV/IDIV forms, not covered here)
+
+ Machine code (shift/rotate group, V picks the shift count - 1 or CL):
+ byte 1            byte 2
+ |1|1|0|1|0|0|v|w| |MOD|OP |R/M  |
+ OP in REG field: 000=ROL 001=ROR 010=RCL 011=RCR 100=SHL/SAL 101=SHR 111=SAR
+
+ Machine code (string primitives - SI/DI/AL(AX) are implicit, so there's
+ no MOD/REG/R-M byte at all, just the opcode):
+ byte 1
+ |1|0|1|0|o|o|o|w|   OP: 010=MOVS 011=CMPS 101=STOS 110=LODS 111=SCAS
+ An optional REP prefix byte (0xF3) may precede any of these, repeating
+ the instruction while CX != 0.
+
+ Machine code (INC/DEC/PUSH/POP register, no MOD/REG/R-M byte - the
+ register is named directly by the opcode's low 3 bits):
+ byte 1
+ |0|1|0|o|o|r|r|r|
+ OP in bits 4-3: 00 = INC, 01 = DEC, 10 = PUSH, 11 = POP. These only
+ operate on the 16-bit general registers.
+
+ Machine code (unconditional JMP, same shape as the conditional jumps
+ below - an 8-bit signed IP-relative displacement):
+ byte 1 (0xEB)     byte 2
+ |1|1|1|0|1|0|1|1| |d|d|d|d|d|d|d|d|
+
+ Machine code (near direct CALL, 16-bit signed IP-relative displacement):
+ byte 1 (0xE8)     byte 2            byte 3
+ |1|1|1|0|1|0|0|0| |d|d|d|d|d|d|d|d| |d|d|d|d|d|d|d|d| (little-endian)
+
+ Machine code (near RET, no operand):
+ byte 1 (0xC3)
+ |1|1|0|0|0|0|1|1|
+
+ Machine code (conditional jumps / loop, 8-bit signed IP-relative displacement):
+ byte 1            byte 2
+ |x|x|x|x|x|x|x|x| |d|d|d|d|d|d|d|d|
+ Opcode identifies the condition directly (no further decoding needed),
+ IP-INC8 is a signed displacement relative to the address of the *next*
+ instruction.
+
+ Reg table
+ =========
+ | REG | W=0 | W=1 |
+ |-----------------|
+ | 000 | AL  | AX  |
+ | 001 | CL  | CX  |
+ | 010 | DL  | DX  |
+ | 011 | BL  | BX  |
+ | 100 | AH  | SP  |
+ | 101 | CH  | BP  |
+ | 110 | DH  | SI  |
+ | 111 | BH  | DI  |
+*/
+
+var RegisterNames map[byte]map[byte]string = map[byte]map[byte]string{
+	0b000: map[byte]string{
+		0: "al",
+		1: "ax",
+	},
+	0b001: map[byte]string{
+		0: "cl",
+		1: "cx",
+	},
+	0b010: map[byte]string{
+		0: "dl",
+		1: "dx",
+	},
+	0b011: map[byte]string{
+		0: "bl",
+		1: "bx",
+	},
+	0b100: map[byte]string{
+		0: "ah",
+		1: "sp",
+	},
+	0b101: map[byte]string{
+		0: "ch",
+		1: "bp",
+	},
+	0b110: map[byte]string{
+		0: "dh",
+		1: "si",
+	},
+	0b111: map[byte]string{
+		0: "bh",
+		1: "di",
+	},
+}
+
+var MemoryEquations map[byte]string = map[byte]string{
+	0b000: "bx + si",
+	0b001: "bx + di",
+	0b010: "bp + si",
+	0b011: "bp + di",
+	0b100: "si",
+	0b101: "di",
+	0b110: "bp",
+	0b111: "bx",
+}
+
+// ArithmeticOpExtensions maps the REG field of an immediate-to-reg/mem
+// instruction to the mnemonic it selects.
+var ArithmeticOpExtensions map[byte]string = map[byte]string{
+	0b000: "add",
+	0b001: "or",
+	0b100: "and",
+	0b101: "sub",
+	0b110: "xor",
+	0b111: "cmp",
+}
+
+// Group3OpMnemonics maps the REG field of a group-3 (0xF6/0xF7)
+// instruction to its mnemonic. Only the TEST/NOT/NEG forms are covered;
+// MUL/IMUL/DIV/IDIV (REG 100-111) aren't decoded by this listing.
+var Group3OpMnemonics map[byte]string = map[byte]string{
+	0b000: "test",
+	0b010: "not",
+	0b011: "neg",
+}
+
+// ShiftOpMnemonics maps the REG field of a shift/rotate (0xD0-0xD3)
+// instruction to its mnemonic.
+var ShiftOpMnemonics map[byte]string = map[byte]string{
+	0b000: "rol",
+	0b001: "ror",
+	0b010: "rcl",
+	0b011: "rcr",
+	0b100: "shl",
+	0b101: "shr",
+	0b111: "sar",
+}
+
+// StringOpMnemonics maps the top 7 bits of a string-primitive opcode to
+// its base mnemonic; the W bit (bit 0) still needs a b/w suffix added to
+// name which width it operates on, since NASM has no separate operand to
+// read that off of.
+var StringOpMnemonics map[byte]string = map[byte]string{
+	0b1010010: "movs",
+	0b1010011: "cmps",
+	0b1010101: "stos",
+	0b1010110: "lods",
+	0b1010111: "scas",
+}
+
+// RegisterOpMnemonics maps the OP bits (bits 4-3) of an INC/DEC/PUSH/POP
+// register opcode to its mnemonic. These only name a 16-bit register, so
+// unlike the other groups there's no W bit to decode.
+var RegisterOpMnemonics map[byte]string = map[byte]string{
+	0b00: "inc",
+	0b01: "dec",
+	0b10: "push",
+	0b11: "pop",
+}
+
+// JumpMnemonics maps a conditional jump / loop opcode byte directly to its
+// NASM mnemonic. Unlike the other groups, these need no further decoding
+// beyond the trailing displacement byte.
+var JumpMnemonics map[byte]string = map[byte]string{
+	0x74: "je",
+	0x7C: "jl",
+	0x7E: "jle",
+	0x72: "jb",
+	0x76: "jbe",
+	0x7A: "jp",
+	0x70: "jo",
+	0x78: "js",
+	0x75: "jne",
+	0x7D: "jnl",
+	0x7F: "jg",
+	0x73: "jnb",
+	0x77: "ja",
+	0x7B: "jnp",
+	0x71: "jno",
+	0x79: "jns",
+	0xE2: "loop",
+	0xE1: "loopz",
+	0xE0: "loopnz",
+	0xE3: "jcxz",
+	0xEB: "jmp",
+}
+
+type DecoderParams struct {
+	Data                []byte
+	Pointer             int
+	DecodedInstructions []string
+	// InstructionOffsets[i] is the byte offset Decode started reading
+	// DecodedInstructions[i] from, so label synthesis can line labels up
+	// with the instruction at a given address.
+	InstructionOffsets []int
+	// JumpLabels maps a target byte offset to the label name synthesised
+	// for it the first time a jump/loop instruction referenced it.
+	JumpLabels map[int]string
+}
+
+type DecoderState func(params *DecoderParams) (DecoderState, error)
+
+// opcodeTableEntry matches the top `Bits` bits of the next byte against
+// Value and, on a match, hands decoding off to Next. Entries are checked
+// most-specific (most bits) first, so a longer opcode would win over a
+// shorter prefix that also matched it; none of the current entries
+// overlap.
+type opcodeTableEntry struct {
+	Bits  uint
+	Value byte
+	Next  DecoderState
+}
+
+var opcodeTable []opcodeTableEntry
+
+func init() {
+	opcodeTable = []opcodeTableEntry{
+		// 8-bit exact matches: conditional jumps, the loop group,
+		// unconditional JMP/CALL/RET.
+		{8, 0x74, JumpState}, {8, 0x7C, JumpState}, {8, 0x7E, JumpState},
+		{8, 0x72, JumpState}, {8, 0x76, JumpState}, {8, 0x7A, JumpState},
+		{8, 0x70, JumpState}, {8, 0x78, JumpState}, {8, 0x75, JumpState},
+		{8, 0x7D, JumpState}, {8, 0x7F, JumpState}, {8, 0x73, JumpState},
+		{8, 0x77, JumpState}, {8, 0x7B, JumpState}, {8, 0x71, JumpState},
+		{8, 0x79, JumpState}, {8, 0xE2, JumpState}, {8, 0xE1, JumpState},
+		{8, 0xE0, JumpState}, {8, 0xE3, JumpState}, {8, 0xEB, JumpState},
+		{8, 0xE8, CallState}, {8, 0xC3, RetState},
+		{8, 0xF3, RepPrefixState},
+		// 7-bit matches: immediate-to-accumulator arithmetic/logic,
+		// TEST (reg/mem and accumulator-immediate), group 3
+		// (TEST-immediate/NOT/NEG) and the string primitives.
+		{7, 0b0000010, AccumulatorImmediateState},
+		{7, 0b0000110, AccumulatorImmediateState},
+		{7, 0b0010010, AccumulatorImmediateState},
+		{7, 0b0010110, AccumulatorImmediateState},
+		{7, 0b0011010, AccumulatorImmediateState},
+		{7, 0b0011110, AccumulatorImmediateState},
+		{7, 0b1010100, AccumulatorImmediateState},
+		{7, 0b1000010, TestRegMemState},
+		{7, 0b1111011, Group3State},
+		{7, 0b1010010, StringOpState},
+		{7, 0b1010011, StringOpState},
+		{7, 0b1010101, StringOpState},
+		{7, 0b1010110, StringOpState},
+		{7, 0b1010111, StringOpState},
+		// 6-bit matches: reg/mem<->reg MOV/arithmetic/logic,
+		// immediate-to-reg/mem arithmetic/logic and the shift/rotate
+		// group.
+		{6, 0b100010, RegAndRegOrMemState},
+		{6, 0b000000, RegAndRegOrMemState},
+		{6, 0b001010, RegAndRegOrMemState},
+		{6, 0b001110, RegAndRegOrMemState},
+		{6, 0b001000, RegAndRegOrMemState},
+		{6, 0b000010, RegAndRegOrMemState},
+		{6, 0b001100, RegAndRegOrMemState},
+		{6, 0b100000, ImmediateToRegMemState},
+		{6, 0b110100, ShiftRotateState},
+		// 5-bit matches: INC/DEC/PUSH/POP register.
+		{5, 0b01000, RegisterOpState},
+		{5, 0b01001, RegisterOpState},
+		{5, 0b01010, RegisterOpState},
+		{5, 0b01011, RegisterOpState},
+		// 4-bit match: immediate to register MOV.
+		{4, 0b1011, ImmediateToRegisterMovState},
+	}
+}
+
+func InitialState(params *DecoderParams) (DecoderState, error) {
+	bytesLeft := len(params.Data) - params.Pointer
+	if bytesLeft == 0 {
+		return nil, nil
+	}
+
+	nextByte := params.Data[params.Pointer]
+
+	if bytesLeft == 1 {
+		// RET, the INC/DEC/PUSH/POP register forms and the string
+		// primitives (without a REP prefix) are the only one-byte
+		// instructions this decoder supports; every other opcode table
+		// entry reads at least a second byte.
+		if nextByte == 0xC3 {
+			return RetState, nil
+		}
+		if top5 := nextByte >> 3; top5 >= 0b01000 && top5 <= 0b01011 {
+			return RegisterOpState, nil
+		}
+		if _, ok := StringOpMnemonics[nextByte>>1]; ok {
+			return StringOpState, nil
+		}
+		return nil, fmt.Errorf("Trailing byte found")
+	}
+
+	for _, entry := range opcodeTable {
+		shifted := nextByte >> (8 - entry.Bits)
+		if shifted == entry.Value {
+			return entry.Next, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown opcode: %b", nextByte)
+}
+
+// mnemonicForRegMemOpcode resolves the 6-bit opcode of a reg/mem<->reg
+// instruction to its mnemonic. MOV keeps its original opcode;
+// ADD/SUB/CMP/AND/OR/XOR share this shape with a different prefix each.
+func mnemonicForRegMemOpcode(opcode byte) (string, error) {
+	switch opcode {
+	case 0b100010:
+		return "mov", nil
+	case 0b000000:
+		return "add", nil
+	case 0b001010:
+		return "sub", nil
+	case 0b001110:
+		return "cmp", nil
+	case 0b001000:
+		return "and", nil
+	case 0b000010:
+		return "or", nil
+	case 0b001100:
+		return "xor", nil
+	default:
+		return "", fmt.Errorf("Unexpected reg/mem opcode: %b", opcode)
+	}
+}
+
+func RegAndRegOrMemState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	byte2 := params.Data[params.Pointer+1]
+
+	mnemonic, err := mnemonicForRegMemOpcode(byte1 >> 2)
+	if err != nil {
+		return nil, err
+	}
+
+	destinationBit := (byte1 >> 1) & 0b1
+	wideBit := (byte1 >> 0) & 0b1
+	mode := (byte2 >> 6) & 0b11
+
+	reg := (byte2 >> 3) & 0b111
+	rm := (byte2 >> 0) & 0b111
+
+	switch mode {
+	case 0b00:
+		MemoryMode(mnemonic, destinationBit, wideBit, reg, rm, params)
+	case 0b01:
+		MemoryMode8BitDisplace(mnemonic, destinationBit, wideBit, reg, rm, params)
+	case 0b10:
+		MemoryMode16BitDisplace(mnemonic, destinationBit, wideBit, reg, rm, params)
+	case 0b11:
+		RegisterMode(mnemonic, destinationBit, wideBit, reg, rm, params)
+	default:
+		return nil, nil
+	}
+
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+func MemoryMode(
+	mnemonic string,
+	destinationBit byte,
+	wideBit byte,
+	reg byte,
+	rm byte,
+	params *DecoderParams,
+) {
+	location1 := fmt.Sprintf("[%s]", MemoryEquations[rm])
+	location2 := RegisterNames[reg][wideBit]
+
+	var destName, srcName string
+	if destinationBit == 1 {
+		destName = location2
+		srcName = location1
+	} else {
+		destName = location1
+		srcName = location2
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s, %s", mnemonic, destName, srcName),
+	)
+	params.Pointer += 2
+}
+
+func MemoryMode8BitDisplace(
+	mnemonic string,
+	destinationBit byte,
+	wideBit byte,
+	reg byte,
+	rm byte,
+	params *DecoderParams,
+) {
+	data := binary.BigEndian.Uint16([]byte{
+		0b0,
+		params.Data[params.Pointer+2],
+	})
+
+	location1 := fmt.Sprintf(
+		"[%s + %d]",
+		MemoryEquations[rm],
+		data,
+	)
+	location2 := RegisterNames[reg][wideBit]
+
+	var destName, srcName string
+	if destinationBit == 1 {
+		destName = location2
+		srcName = location1
+	} else {
+		destName = location1
+		srcName = location2
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s, %s", mnemonic, destName, srcName),
+	)
+	params.Pointer += 3
+}
+
+func MemoryMode16BitDisplace(
+	mnemonic string,
+	destinationBit byte,
+	wideBit byte,
+	reg byte,
+	rm byte,
+	params *DecoderParams,
+) {
+	data := binary.BigEndian.Uint16([]byte{
+		params.Data[params.Pointer+3],
+		params.Data[params.Pointer+2],
+	})
+
+	location1 := fmt.Sprintf(
+		"[%s + %d]",
+		MemoryEquations[rm],
+		data,
+	)
+	location2 := RegisterNames[reg][wideBit]
+
+	var destName, srcName string
+	if destinationBit == 1 {
+		destName = location2
+		srcName = location1
+	} else {
+		destName = location1
+		srcName = location2
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s, %s", mnemonic, destName, srcName),
+	)
+	params.Pointer += 4
+}
+
+func RegisterMode(
+	mnemonic string,
+	destinationBit byte,
+	wideBit byte,
+	reg byte,
+	rm byte,
+	params *DecoderParams,
+) {
+	var destRegName, srcRegName string
+	if destinationBit == 1 {
+		destRegName = RegisterNames[reg][wideBit]
+		srcRegName = RegisterNames[rm][wideBit]
+	} else {
+		destRegName = RegisterNames[rm][wideBit]
+		srcRegName = RegisterNames[reg][wideBit]
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s, %s", mnemonic, destRegName, srcRegName),
+	)
+	params.Pointer += 2
+}
+
+// ImmediateToRegMemState decodes `100000sw` arithmetic instructions, where
+// the REG field of the second byte picks ADD/OR/AND/SUB/XOR/CMP instead
+// of naming a register.
+func ImmediateToRegMemState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	byte2 := params.Data[params.Pointer+1]
+
+	signExtendBit := (byte1 >> 1) & 0b1
+	wideBit := (byte1 >> 0) & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := (byte2 >> 0) & 0b111
+
+	mnemonic, ok := ArithmeticOpExtensions[opField]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported arithmetic extension: %b", opField)
+	}
+
+	var destName string
+	var dataOffset int
+	switch mode {
+	case 0b00:
+		destName = fmt.Sprintf("[%s]", MemoryEquations[rm])
+		dataOffset = params.Pointer + 2
+	case 0b01:
+		disp := uint16(params.Data[params.Pointer+2])
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		dataOffset = params.Pointer + 3
+	case 0b10:
+		disp := binary.BigEndian.Uint16([]byte{
+			params.Data[params.Pointer+3],
+			params.Data[params.Pointer+2],
+		})
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		dataOffset = params.Pointer + 4
+	case 0b11:
+		destName = RegisterNames[rm][wideBit]
+		dataOffset = params.Pointer + 2
+	}
+
+	var data uint16
+	var size string
+	if wideBit == 1 && signExtendBit == 0 {
+		data = binary.BigEndian.Uint16([]byte{
+			params.Data[dataOffset+1],
+			params.Data[dataOffset],
+		})
+		size = "word"
+		params.Pointer = dataOffset + 2
+	} else {
+		data = uint16(params.Data[dataOffset])
+		if wideBit == 1 {
+			size = "word"
+		} else {
+			size = "byte"
+		}
+		params.Pointer = dataOffset + 1
+	}
+
+	if mode == 0b11 {
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("%s %s, %d", mnemonic, destName, data),
+		)
+	} else {
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("%s %s %s, %d", mnemonic, size, destName, data),
+		)
+	}
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// AccumulatorImmediateState decodes the short
+// `add/or/and/sub/xor/cmp/test ax, imm` forms that operate implicitly on
+// AL/AX.
+func AccumulatorImmediateState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	wideBit := byte1 & 0b1
+
+	var mnemonic string
+	switch byte1 >> 1 {
+	case 0b0000010:
+		mnemonic = "add"
+	case 0b0000110:
+		mnemonic = "or"
+	case 0b0010010:
+		mnemonic = "and"
+	case 0b0010110:
+		mnemonic = "sub"
+	case 0b0011010:
+		mnemonic = "xor"
+	case 0b0011110:
+		mnemonic = "cmp"
+	case 0b1010100:
+		mnemonic = "test"
+	default:
+		return nil, fmt.Errorf("Unexpected accumulator opcode: %b", byte1)
+	}
+
+	destRegName := RegisterNames[0b000][wideBit]
+
+	var data uint16
+	if wideBit == 1 {
+		data = binary.BigEndian.Uint16([]byte{
+			params.Data[params.Pointer+2],
+			params.Data[params.Pointer+1],
+		})
+		params.Pointer += 3
+	} else {
+		data = uint16(params.Data[params.Pointer+1])
+		params.Pointer += 2
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s, %d", mnemonic, destRegName, data),
+	)
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// RegisterOpState decodes the one-byte INC/DEC/PUSH/POP register forms,
+// which name their (always 16-bit) register directly in the opcode's low
+// 3 bits instead of via a MOD/REG/R-M byte.
+func RegisterOpState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+
+	op := (byte1 >> 3) & 0b11
+	reg := byte1 & 0b111
+
+	mnemonic, ok := RegisterOpMnemonics[op]
+	if !ok {
+		return nil, fmt.Errorf("Unexpected register-op opcode: %b", byte1)
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s", mnemonic, RegisterNames[reg][1]),
+	)
+	params.Pointer += 1
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// TestRegMemState decodes TEST reg/mem and register (`1000 010w`). It's
+// the same shape as RegAndRegOrMemState's MOD/REG/R-M byte but has no D
+// bit, since TEST discards its result either way.
+func TestRegMemState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	byte2 := params.Data[params.Pointer+1]
+
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	reg := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	regName := RegisterNames[reg][wideBit]
+
+	var rmName string
+	var consumed int
+	switch mode {
+	case 0b00:
+		rmName = fmt.Sprintf("[%s]", MemoryEquations[rm])
+		consumed = 2
+	case 0b01:
+		disp := uint16(params.Data[params.Pointer+2])
+		rmName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		consumed = 3
+	case 0b10:
+		disp := binary.BigEndian.Uint16([]byte{
+			params.Data[params.Pointer+3],
+			params.Data[params.Pointer+2],
+		})
+		rmName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		consumed = 4
+	case 0b11:
+		rmName = RegisterNames[rm][wideBit]
+		consumed = 2
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("test %s, %s", rmName, regName),
+	)
+	params.Pointer += consumed
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// Group3State decodes the group-3 TEST-immediate/NOT/NEG forms
+// (`1111 011w`), where the REG field of the second byte picks the
+// operation instead of naming a register.
+func Group3State(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	byte2 := params.Data[params.Pointer+1]
+
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := Group3OpMnemonics[opField]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported group-3 extension: %b", opField)
+	}
+
+	var destName string
+	var dataOffset int
+	switch mode {
+	case 0b00:
+		destName = fmt.Sprintf("[%s]", MemoryEquations[rm])
+		dataOffset = params.Pointer + 2
+	case 0b01:
+		disp := uint16(params.Data[params.Pointer+2])
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		dataOffset = params.Pointer + 3
+	case 0b10:
+		disp := binary.BigEndian.Uint16([]byte{
+			params.Data[params.Pointer+3],
+			params.Data[params.Pointer+2],
+		})
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		dataOffset = params.Pointer + 4
+	case 0b11:
+		destName = RegisterNames[rm][wideBit]
+		dataOffset = params.Pointer + 2
+	}
+
+	size := "byte"
+	if wideBit == 1 {
+		size = "word"
+	}
+
+	if mnemonic != "test" {
+		params.Pointer = dataOffset
+		if mode == 0b11 {
+			params.DecodedInstructions = append(
+				params.DecodedInstructions,
+				fmt.Sprintf("%s %s", mnemonic, destName),
+			)
+		} else {
+			params.DecodedInstructions = append(
+				params.DecodedInstructions,
+				fmt.Sprintf("%s %s %s", mnemonic, size, destName),
+			)
+		}
+		params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+		return InitialState, nil
+	}
+
+	var data uint16
+	if wideBit == 1 {
+		data = binary.BigEndian.Uint16([]byte{
+			params.Data[dataOffset+1],
+			params.Data[dataOffset],
+		})
+		params.Pointer = dataOffset + 2
+	} else {
+		data = uint16(params.Data[dataOffset])
+		params.Pointer = dataOffset + 1
+	}
+
+	if mode == 0b11 {
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("test %s, %d", destName, data),
+		)
+	} else {
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("test %s %s, %d", size, destName, data),
+		)
+	}
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// ShiftRotateState decodes the shift/rotate group (`1101 00vw`), where
+// the REG field of the second byte picks the operation and the V bit
+// picks the shift count: 1 (V=0) or the value in CL (V=1).
+func ShiftRotateState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	byte2 := params.Data[params.Pointer+1]
+
+	countBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := ShiftOpMnemonics[opField]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported shift/rotate extension: %b", opField)
+	}
+
+	var destName string
+	var consumed int
+	switch mode {
+	case 0b00:
+		destName = fmt.Sprintf("[%s]", MemoryEquations[rm])
+		consumed = 2
+	case 0b01:
+		disp := uint16(params.Data[params.Pointer+2])
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		consumed = 3
+	case 0b10:
+		disp := binary.BigEndian.Uint16([]byte{
+			params.Data[params.Pointer+3],
+			params.Data[params.Pointer+2],
+		})
+		destName = fmt.Sprintf("[%s + %d]", MemoryEquations[rm], disp)
+		consumed = 4
+	case 0b11:
+		destName = RegisterNames[rm][wideBit]
+		consumed = 2
+	}
+
+	countOperand := "1"
+	if countBit == 1 {
+		countOperand = "cl"
+	}
+
+	if mode == 0b11 {
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("%s %s, %s", mnemonic, destName, countOperand),
+		)
+	} else {
+		size := "byte"
+		if wideBit == 1 {
+			size = "word"
+		}
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("%s %s %s, %s", mnemonic, size, destName, countOperand),
+		)
+	}
+	params.Pointer += consumed
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// StringOpState decodes the string primitives (MOVS/CMPS/STOS/LODS/SCAS).
+// SI/DI/AL(AX) are all implicit, so the opcode byte is the whole
+// instruction; the W bit becomes the b/w suffix NASM uses to tell the
+// width apart in the absence of any operand to read it off of.
+func StringOpState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+
+	mnemonic, ok := StringOpMnemonics[byte1>>1]
+	if !ok {
+		return nil, fmt.Errorf("Unexpected string-op opcode: %b", byte1)
+	}
+
+	suffix := "b"
+	if byte1&0b1 == 1 {
+		suffix = "w"
+	}
+
+	params.DecodedInstructions = append(params.DecodedInstructions, mnemonic+suffix)
+	params.Pointer += 1
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// RepPrefixState decodes the REP prefix (0xF3), which only ever precedes
+// a string primitive. It folds the prefix and the instruction it repeats
+// into a single decoded line, the same as NASM's own `rep movsb` syntax.
+func RepPrefixState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	if params.Pointer+2 > len(params.Data) {
+		return nil, fmt.Errorf("Trailing REP prefix with no instruction")
+	}
+
+	byte2 := params.Data[params.Pointer+1]
+	mnemonic, ok := StringOpMnemonics[byte2>>1]
+	if !ok {
+		return nil, fmt.Errorf("REP prefix not followed by a string instruction: %b", byte2)
+	}
+
+	suffix := "b"
+	if byte2&0b1 == 1 {
+		suffix = "w"
+	}
+
+	params.DecodedInstructions = append(params.DecodedInstructions, "rep "+mnemonic+suffix)
+	params.Pointer += 2
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// CallState decodes the near direct CALL (`1110 1000`), the only
+// instruction in this listing whose IP-relative displacement is 16 bits
+// rather than 8. It synthesises a label the same way JumpState does.
+func CallState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	displacement := int16(binary.LittleEndian.Uint16(params.Data[params.Pointer+1 : params.Pointer+3]))
+	params.Pointer += 3
+
+	target := params.Pointer + int(displacement)
+	label, seen := params.JumpLabels[target]
+	if !seen {
+		label = fmt.Sprintf("label_%d", len(params.JumpLabels))
+		params.JumpLabels[target] = label
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("call %s", label),
+	)
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// RetState decodes the near RET (`1100 0011`), which takes no operand.
+func RetState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	params.DecodedInstructions = append(params.DecodedInstructions, "ret")
+	params.Pointer += 1
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// JumpState decodes conditional jumps, the loop group and the
+// unconditional JMP (which shares the same one-byte-opcode-plus-signed-
+// displacement shape), all of which are a one-byte opcode followed by a
+// signed 8-bit displacement relative to the address of the instruction
+// that follows. Since NASM has no notion of "jump 6 bytes forward", the
+// target address is recorded and replaced with a synthesised label;
+// ResolveLabels inserts the matching `label_N:` once every instruction
+// has been decoded.
+func JumpState(params *DecoderParams) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+	mnemonic, ok := JumpMnemonics[byte1]
+	if !ok {
+		return nil, fmt.Errorf("Unexpected jump opcode: %b", byte1)
+	}
+
+	displacement := int8(params.Data[params.Pointer+1])
+	params.Pointer += 2
+
+	target := params.Pointer + int(displacement)
+	label, seen := params.JumpLabels[target]
+	if !seen {
+		label = fmt.Sprintf("label_%d", len(params.JumpLabels))
+		params.JumpLabels[target] = label
+	}
+
+	params.DecodedInstructions = append(
+		params.DecodedInstructions,
+		fmt.Sprintf("%s %s", mnemonic, label),
+	)
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+func ImmediateToRegisterMovState(
+	params *DecoderParams,
+) (DecoderState, error) {
+	startOffset := params.Pointer
+	byte1 := params.Data[params.Pointer]
+
+	wideBit := (byte1 >> 3) & 0b1
+	reg := (byte1 >> 0) & 0b111
+
+	if wideBit == 1 {
+		dataBytes := []byte{
+			params.Data[params.Pointer+2],
+			params.Data[params.Pointer+1],
+		}
+		data := binary.BigEndian.Uint16(dataBytes)
+
+		destRegName := RegisterNames[reg][wideBit]
+
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("mov %s, %d", destRegName, data),
+		)
+		params.Pointer += 3
+	} else {
+		dataBytes := []byte{
+			0b0,
+			params.Data[params.Pointer+1],
+		}
+		data := binary.BigEndian.Uint16(dataBytes)
+
+		destRegName := RegisterNames[reg][wideBit]
+
+		params.DecodedInstructions = append(
+			params.DecodedInstructions,
+			fmt.Sprintf("mov %s, %d", destRegName, data),
+		)
+		params.Pointer += 2
+	}
+
+	params.InstructionOffsets = append(params.InstructionOffsets, startOffset)
+	return InitialState, nil
+}
+
+// ResolveLabels walks the decoded instructions in address order and
+// inserts a `label_N:` line wherever a jump/loop instruction targeted
+// that address, so the output round-trips through NASM.
+func ResolveLabels(params *DecoderParams) []string {
+	if len(params.JumpLabels) == 0 {
+		return params.DecodedInstructions
+	}
+
+	resolved := make([]string, 0, len(params.DecodedInstructions)+len(params.JumpLabels))
+	for i, instruction := range params.DecodedInstructions {
+		if label, ok := params.JumpLabels[params.InstructionOffsets[i]]; ok {
+			resolved = append(resolved, label+":")
+		}
+		resolved = append(resolved, instruction)
+	}
+	return resolved
+}
+
+func Decode(input []byte) []string {
+	var state DecoderState = InitialState
+	var params DecoderParams = DecoderParams{
+		Data:                input,
+		Pointer:             0,
+		DecodedInstructions: []string{},
+		InstructionOffsets:  []int{},
+		JumpLabels:          map[int]string{},
+	}
+
+	var err error
+	for state != nil {
+		state, err = state(&params)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return ResolveLabels(&params)
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("Usage: decoder <FILENAME>")
+		os.Exit(1)
+	}
+	inputFilename := os.Args[1]
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		panic(err)
+	}
+
+	inputData, err := io.ReadAll(inputFile)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("bits 16\n")
+	decodedInstructions := Decode(inputData)
+	for _, instruction := range decodedInstructions {
+		fmt.Println(instruction)
+	}
+}
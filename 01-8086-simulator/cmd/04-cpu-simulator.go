@@ -0,0 +1,662 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ Simulating the 8086 instructions decoded by 03-arithmetic-and-jump-decoder.go
+
+ Rather than printing assembly, this cycle decodes one instruction at a
+ time straight out of CPU memory and executes it against an in-memory
+ 8086: 8 general registers (with the al/ah/bl/bh/cl/ch/dl/dh byte views
+ onto ax/bx/cx/dx), the flags touched by ADD/SUB/CMP (ZF/SF/OF/CF/PF/AF),
+ an instruction pointer and a 1 MiB byte array for memory.
+
+ Each cycle:
+  1. fetch + decode the instruction at CPU.IP
+  2. dispatch to a per-mnemonic handler that mutates registers/memory/flags
+  3. advance IP (to the next instruction, or to a jump target)
+
+ Only part of what 03-arithmetic-and-jump-decoder.go decodes is
+ simulated: MOV, ADD/SUB/CMP (reg/mem<->reg, immediate to reg/mem,
+ immediate to accumulator) and the conditional jump / loop group. Segment
+ registers aren't modelled - memory is addressed by the 16-bit effective
+ address alone, so only its first 64 KiB is reachable - same
+ simplification the decoder already makes for MOD=00 R/M=110.
+*/
+
+const memorySize = 1 << 20 // 1 MiB
+
+// wideRegisterNames mirrors the decoder's RegisterNames table, but keyed
+// purely on the REG/R/M bit pattern -> name, since the simulator needs to
+// read and write those registers rather than print them.
+var wideRegisterNames map[byte]map[byte]string = map[byte]map[byte]string{
+	0b000: {0: "al", 1: "ax"},
+	0b001: {0: "cl", 1: "cx"},
+	0b010: {0: "dl", 1: "dx"},
+	0b011: {0: "bl", 1: "bx"},
+	0b100: {0: "ah", 1: "sp"},
+	0b101: {0: "ch", 1: "bp"},
+	0b110: {0: "dh", 1: "si"},
+	0b111: {0: "bh", 1: "di"},
+}
+
+var memoryEquationRegs map[byte][]string = map[byte][]string{
+	0b000: {"bx", "si"},
+	0b001: {"bx", "di"},
+	0b010: {"bp", "si"},
+	0b011: {"bp", "di"},
+	0b100: {"si"},
+	0b101: {"di"},
+	0b110: {"bp"},
+	0b111: {"bx"},
+}
+
+var simArithmeticOpExtensions map[byte]string = map[byte]string{
+	0b000: "add",
+	0b101: "sub",
+	0b111: "cmp",
+}
+
+var simJumpMnemonics map[byte]string = map[byte]string{
+	0x74: "je", 0x7C: "jl", 0x7E: "jle", 0x72: "jb", 0x76: "jbe",
+	0x7A: "jp", 0x70: "jo", 0x78: "js", 0x75: "jne", 0x7D: "jnl",
+	0x7F: "jg", 0x73: "jnb", 0x77: "ja", 0x7B: "jnp", 0x71: "jno",
+	0x79: "jns", 0xE2: "loop", 0xE1: "loopz", 0xE0: "loopnz", 0xE3: "jcxz",
+}
+
+// registerAliases describes how an 8-bit register name maps onto a byte
+// of its 16-bit parent.
+var registerAliases = map[string]struct {
+	Parent string
+	High   bool
+}{
+	"al": {"ax", false}, "ah": {"ax", true},
+	"bl": {"bx", false}, "bh": {"bx", true},
+	"cl": {"cx", false}, "ch": {"cx", true},
+	"dl": {"dx", false}, "dh": {"dx", true},
+}
+
+type operandKind int
+
+const (
+	opRegister operandKind = iota
+	opImmediate
+	opMemory
+)
+
+// operand is a decoded instruction argument. Not every field is used by
+// every Kind: BaseRegs/Displacement only apply to opMemory, Value only to
+// opImmediate (and, for jump instructions, the resolved target address).
+type operand struct {
+	Kind         operandKind
+	Register     string
+	Value        int64
+	BaseRegs     []string
+	Displacement int
+	Wide         bool
+}
+
+// instruction is the minimal decode the simulator needs to execute a
+// step - it is deliberately local to this file rather than the shared AST
+// other requests in this backlog introduce for formatting.
+type instruction struct {
+	Mnemonic string
+	Dst      operand
+	Src      operand
+}
+
+type Flags struct {
+	ZF, SF, OF, CF, PF, AF bool
+}
+
+type CPU struct {
+	Registers     map[string]uint16
+	Flags         Flags
+	IP            uint16
+	Memory        []byte
+	ProgramLength uint16
+}
+
+func NewCPU() *CPU {
+	return &CPU{
+		Registers: map[string]uint16{
+			"ax": 0, "bx": 0, "cx": 0, "dx": 0,
+			"sp": 0, "bp": 0, "si": 0, "di": 0,
+		},
+		Memory: make([]byte, memorySize),
+	}
+}
+
+func (cpu *CPU) LoadProgram(program []byte) {
+	copy(cpu.Memory, program)
+	cpu.IP = 0
+	cpu.ProgramLength = uint16(len(program))
+}
+
+// RegisterDelta captures a register's value immediately before and after
+// a single Step, for use by a Trace callback.
+type RegisterDelta struct {
+	Before uint16
+	After  uint16
+}
+
+// TraceEvent is reported to the callback passed to Run after each
+// successfully executed instruction.
+type TraceEvent struct {
+	IP             uint16
+	Instruction    instruction
+	RegisterDeltas map[string]RegisterDelta
+	FlagsBefore    Flags
+	FlagsAfter     Flags
+}
+
+// Step decodes and executes a single instruction at CPU.IP. It returns
+// false once IP has run off the end of the loaded program.
+func (cpu *CPU) Step() (bool, *TraceEvent, error) {
+	if cpu.IP >= cpu.ProgramLength {
+		return false, nil, nil
+	}
+
+	instr, length, err := decodeAt(cpu.Memory, cpu.IP)
+	if err != nil {
+		return false, nil, err
+	}
+	fallthroughIP := cpu.IP + uint16(length)
+
+	before := make(map[string]uint16, len(cpu.Registers))
+	for name, value := range cpu.Registers {
+		before[name] = value
+	}
+	flagsBefore := cpu.Flags
+
+	if err := execute(cpu, instr, fallthroughIP); err != nil {
+		return false, nil, err
+	}
+
+	deltas := map[string]RegisterDelta{}
+	for name, value := range cpu.Registers {
+		if before[name] != value {
+			deltas[name] = RegisterDelta{Before: before[name], After: value}
+		}
+	}
+
+	return true, &TraceEvent{
+		IP:             cpu.IP,
+		Instruction:    instr,
+		RegisterDeltas: deltas,
+		FlagsBefore:    flagsBefore,
+		FlagsAfter:     cpu.Flags,
+	}, nil
+}
+
+// Run steps the CPU to completion, invoking trace (if non-nil) after
+// every instruction that executes.
+func (cpu *CPU) Run(trace func(TraceEvent)) error {
+	for {
+		ok, event, err := cpu.Step()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if trace != nil && event != nil {
+			trace(*event)
+		}
+	}
+}
+
+func decodeAt(memory []byte, ip uint16) (instruction, int, error) {
+	pointer := int(ip)
+	if pointer+1 >= len(memory) {
+		return instruction{}, 0, fmt.Errorf("Instruction at offset %d runs past the end of memory", pointer)
+	}
+	byte1 := memory[pointer]
+
+	switch {
+	case isJumpOpcode(byte1):
+		return decodeJump(memory, ip)
+	case byte1>>1 == 0b0000010, byte1>>1 == 0b0010110, byte1>>1 == 0b0011110:
+		return decodeAccumulatorImm(memory, pointer)
+	case byte1>>2 == 0b100010, byte1>>2 == 0b000000, byte1>>2 == 0b001010, byte1>>2 == 0b001110:
+		return decodeRegMemInstr(memory, pointer)
+	case byte1>>2 == 0b100000:
+		return decodeImmToRegMem(memory, pointer)
+	case byte1>>4 == 0b1011:
+		return decodeImmToRegMov(memory, pointer)
+	default:
+		return instruction{}, 0, fmt.Errorf("Unknown opcode: %b at offset %d", byte1, pointer)
+	}
+}
+
+func isJumpOpcode(b byte) bool {
+	_, ok := simJumpMnemonics[b]
+	return ok
+}
+
+func mnemonicForSimRegMemOpcode(opcode byte) (string, error) {
+	switch opcode {
+	case 0b100010:
+		return "mov", nil
+	case 0b000000:
+		return "add", nil
+	case 0b001010:
+		return "sub", nil
+	case 0b001110:
+		return "cmp", nil
+	default:
+		return "", fmt.Errorf("Unexpected reg/mem opcode: %b", opcode)
+	}
+}
+
+// decodeRM decodes the operand named by a MOD/R-M pair, returning the
+// number of displacement bytes it consumed beyond the two instruction
+// bytes every reg/mem encoding starts with.
+func decodeRM(memory []byte, pointer int, mode byte, rm byte, wideBit byte) (operand, int) {
+	switch mode {
+	case 0b01:
+		disp := int8(memory[pointer+2])
+		return operand{Kind: opMemory, BaseRegs: memoryEquationRegs[rm], Displacement: int(disp), Wide: wideBit == 1}, 1
+	case 0b10:
+		disp := int16(binary.LittleEndian.Uint16(memory[pointer+2 : pointer+4]))
+		return operand{Kind: opMemory, BaseRegs: memoryEquationRegs[rm], Displacement: int(disp), Wide: wideBit == 1}, 2
+	case 0b11:
+		return operand{Kind: opRegister, Register: wideRegisterNames[rm][wideBit], Wide: wideBit == 1}, 0
+	default: // 0b00
+		return operand{Kind: opMemory, BaseRegs: memoryEquationRegs[rm], Wide: wideBit == 1}, 0
+	}
+}
+
+func decodeRegMemInstr(memory []byte, pointer int) (instruction, int, error) {
+	byte1 := memory[pointer]
+	byte2 := memory[pointer+1]
+
+	mnemonic, err := mnemonicForSimRegMemOpcode(byte1 >> 2)
+	if err != nil {
+		return instruction{}, 0, err
+	}
+
+	destinationBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	reg := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	regOperand := operand{Kind: opRegister, Register: wideRegisterNames[reg][wideBit], Wide: wideBit == 1}
+	rmOperand, extra := decodeRM(memory, pointer, mode, rm, wideBit)
+
+	var dst, src operand
+	if destinationBit == 1 {
+		dst, src = regOperand, rmOperand
+	} else {
+		dst, src = rmOperand, regOperand
+	}
+
+	return instruction{Mnemonic: mnemonic, Dst: dst, Src: src}, 2 + extra, nil
+}
+
+func decodeImmToRegMem(memory []byte, pointer int) (instruction, int, error) {
+	byte1 := memory[pointer]
+	byte2 := memory[pointer+1]
+
+	signExtendBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := simArithmeticOpExtensions[opField]
+	if !ok {
+		return instruction{}, 0, fmt.Errorf("Unsupported arithmetic extension: %b", opField)
+	}
+
+	dst, extra := decodeRM(memory, pointer, mode, rm, wideBit)
+	dataOffset := pointer + 2 + extra
+
+	var data int64
+	var dataLen int
+	switch {
+	case wideBit == 1 && signExtendBit == 0:
+		data = int64(binary.LittleEndian.Uint16(memory[dataOffset : dataOffset+2]))
+		dataLen = 2
+	case wideBit == 1 && signExtendBit == 1:
+		// Sign-extend the byte to match the 16-bit operand.
+		data = int64(int8(memory[dataOffset]))
+		dataLen = 1
+	default:
+		// 8-bit operand: the immediate is just a raw byte, not sign-extended.
+		data = int64(memory[dataOffset])
+		dataLen = 1
+	}
+
+	src := operand{Kind: opImmediate, Value: data, Wide: wideBit == 1}
+	return instruction{Mnemonic: mnemonic, Dst: dst, Src: src}, 2 + extra + dataLen, nil
+}
+
+func decodeAccumulatorImm(memory []byte, pointer int) (instruction, int, error) {
+	byte1 := memory[pointer]
+	wideBit := byte1 & 0b1
+
+	var mnemonic string
+	switch byte1 >> 1 {
+	case 0b0000010:
+		mnemonic = "add"
+	case 0b0010110:
+		mnemonic = "sub"
+	case 0b0011110:
+		mnemonic = "cmp"
+	default:
+		return instruction{}, 0, fmt.Errorf("Unexpected accumulator opcode: %b", byte1)
+	}
+
+	dst := operand{Kind: opRegister, Register: wideRegisterNames[0b000][wideBit], Wide: wideBit == 1}
+
+	var data int64
+	var length int
+	if wideBit == 1 {
+		data = int64(binary.LittleEndian.Uint16(memory[pointer+1 : pointer+3]))
+		length = 3
+	} else {
+		data = int64(memory[pointer+1])
+		length = 2
+	}
+
+	src := operand{Kind: opImmediate, Value: data, Wide: wideBit == 1}
+	return instruction{Mnemonic: mnemonic, Dst: dst, Src: src}, length, nil
+}
+
+func decodeImmToRegMov(memory []byte, pointer int) (instruction, int, error) {
+	byte1 := memory[pointer]
+	wideBit := (byte1 >> 3) & 0b1
+	reg := byte1 & 0b111
+
+	dst := operand{Kind: opRegister, Register: wideRegisterNames[reg][wideBit], Wide: wideBit == 1}
+
+	var data int64
+	var length int
+	if wideBit == 1 {
+		data = int64(binary.LittleEndian.Uint16(memory[pointer+1 : pointer+3]))
+		length = 3
+	} else {
+		data = int64(memory[pointer+1])
+		length = 2
+	}
+
+	src := operand{Kind: opImmediate, Value: data, Wide: wideBit == 1}
+	return instruction{Mnemonic: "mov", Dst: dst, Src: src}, length, nil
+}
+
+func decodeJump(memory []byte, ip uint16) (instruction, int, error) {
+	pointer := int(ip)
+	byte1 := memory[pointer]
+	mnemonic, ok := simJumpMnemonics[byte1]
+	if !ok {
+		return instruction{}, 0, fmt.Errorf("Unexpected jump opcode: %b", byte1)
+	}
+
+	displacement := int8(memory[pointer+1])
+	target := int(ip) + 2 + int(displacement)
+
+	return instruction{
+		Mnemonic: mnemonic,
+		Dst:      operand{Kind: opImmediate, Value: int64(target)},
+	}, 2, nil
+}
+
+func getRegisterValue(cpu *CPU, name string, wide bool) uint16 {
+	if wide {
+		return cpu.Registers[name]
+	}
+	alias, ok := registerAliases[name]
+	if !ok {
+		return cpu.Registers[name]
+	}
+	parent := cpu.Registers[alias.Parent]
+	if alias.High {
+		return (parent >> 8) & 0xFF
+	}
+	return parent & 0xFF
+}
+
+func setRegisterValue(cpu *CPU, name string, wide bool, value uint16) {
+	if wide {
+		cpu.Registers[name] = value
+		return
+	}
+	alias, ok := registerAliases[name]
+	if !ok {
+		cpu.Registers[name] = value & 0xFF
+		return
+	}
+	parent := cpu.Registers[alias.Parent]
+	if alias.High {
+		cpu.Registers[alias.Parent] = (parent & 0x00FF) | ((value & 0xFF) << 8)
+	} else {
+		cpu.Registers[alias.Parent] = (parent & 0xFF00) | (value & 0xFF)
+	}
+}
+
+func effectiveAddress(cpu *CPU, op operand) uint16 {
+	addr := op.Displacement
+	for _, reg := range op.BaseRegs {
+		addr += int(cpu.Registers[reg])
+	}
+	return uint16(addr)
+}
+
+func readMemory(cpu *CPU, addr uint16, wide bool) uint16 {
+	if wide {
+		return binary.LittleEndian.Uint16(cpu.Memory[addr : addr+2])
+	}
+	return uint16(cpu.Memory[addr])
+}
+
+func writeMemory(cpu *CPU, addr uint16, wide bool, value uint16) {
+	if wide {
+		binary.LittleEndian.PutUint16(cpu.Memory[addr:addr+2], value)
+	} else {
+		cpu.Memory[addr] = byte(value)
+	}
+}
+
+func getOperand(cpu *CPU, op operand) uint16 {
+	switch op.Kind {
+	case opRegister:
+		return getRegisterValue(cpu, op.Register, op.Wide)
+	case opImmediate:
+		return uint16(op.Value)
+	case opMemory:
+		return readMemory(cpu, effectiveAddress(cpu, op), op.Wide)
+	default:
+		return 0
+	}
+}
+
+func setOperand(cpu *CPU, op operand, value uint16) {
+	switch op.Kind {
+	case opRegister:
+		setRegisterValue(cpu, op.Register, op.Wide, value)
+	case opMemory:
+		writeMemory(cpu, effectiveAddress(cpu, op), op.Wide, value)
+	}
+}
+
+func parityEven(b byte) bool {
+	ones := 0
+	for i := 0; i < 8; i++ {
+		if b&(1<<uint(i)) != 0 {
+			ones++
+		}
+	}
+	return ones%2 == 0
+}
+
+// updateArithmeticFlags computes a +/- b (mod the operand width) and sets
+// flags to match, returning the masked result so callers can store it.
+func updateArithmeticFlags(flags *Flags, a, b uint32, wide bool, subtract bool) uint16 {
+	width := uint(8)
+	if wide {
+		width = 16
+	}
+	mask := uint32(1)<<width - 1
+	signBit := uint32(1) << (width - 1)
+
+	a &= mask
+	b &= mask
+
+	var raw uint32
+	if subtract {
+		raw = a - b
+	} else {
+		raw = a + b
+	}
+	masked := raw & mask
+
+	flags.ZF = masked == 0
+	flags.SF = masked&signBit != 0
+	flags.PF = parityEven(byte(masked & 0xFF))
+
+	aSign := a&signBit != 0
+	bSign := b&signBit != 0
+	rSign := masked&signBit != 0
+
+	if subtract {
+		flags.CF = a < b
+		flags.OF = aSign != bSign && rSign != aSign
+		flags.AF = (a & 0xF) < (b & 0xF)
+	} else {
+		flags.CF = raw > mask
+		flags.OF = aSign == bSign && rSign != aSign
+		flags.AF = (a&0xF)+(b&0xF) > 0xF
+	}
+
+	return uint16(masked)
+}
+
+func shouldJump(cpu *CPU, mnemonic string) bool {
+	flags := cpu.Flags
+	switch mnemonic {
+	case "je":
+		return flags.ZF
+	case "jne":
+		return !flags.ZF
+	case "jl":
+		return flags.SF != flags.OF
+	case "jnl":
+		return flags.SF == flags.OF
+	case "jle":
+		return flags.ZF || flags.SF != flags.OF
+	case "jg":
+		return !flags.ZF && flags.SF == flags.OF
+	case "jb":
+		return flags.CF
+	case "jnb":
+		return !flags.CF
+	case "jbe":
+		return flags.CF || flags.ZF
+	case "ja":
+		return !flags.CF && !flags.ZF
+	case "jp":
+		return flags.PF
+	case "jnp":
+		return !flags.PF
+	case "jo":
+		return flags.OF
+	case "jno":
+		return !flags.OF
+	case "js":
+		return flags.SF
+	case "jns":
+		return !flags.SF
+	case "jcxz":
+		return cpu.Registers["cx"] == 0
+	case "loop":
+		cpu.Registers["cx"]--
+		return cpu.Registers["cx"] != 0
+	case "loopz":
+		cpu.Registers["cx"]--
+		return cpu.Registers["cx"] != 0 && flags.ZF
+	case "loopnz":
+		cpu.Registers["cx"]--
+		return cpu.Registers["cx"] != 0 && !flags.ZF
+	default:
+		return false
+	}
+}
+
+func execute(cpu *CPU, instr instruction, fallthroughIP uint16) error {
+	switch instr.Mnemonic {
+	case "mov":
+		setOperand(cpu, instr.Dst, getOperand(cpu, instr.Src))
+		cpu.IP = fallthroughIP
+	case "add":
+		a, b := uint32(getOperand(cpu, instr.Dst)), uint32(getOperand(cpu, instr.Src))
+		result := updateArithmeticFlags(&cpu.Flags, a, b, instr.Dst.Wide, false)
+		setOperand(cpu, instr.Dst, result)
+		cpu.IP = fallthroughIP
+	case "sub":
+		a, b := uint32(getOperand(cpu, instr.Dst)), uint32(getOperand(cpu, instr.Src))
+		result := updateArithmeticFlags(&cpu.Flags, a, b, instr.Dst.Wide, true)
+		setOperand(cpu, instr.Dst, result)
+		cpu.IP = fallthroughIP
+	case "cmp":
+		a, b := uint32(getOperand(cpu, instr.Dst)), uint32(getOperand(cpu, instr.Src))
+		updateArithmeticFlags(&cpu.Flags, a, b, instr.Dst.Wide, true)
+		cpu.IP = fallthroughIP
+	default:
+		// decodeAt only ever produces a mnemonic outside the cases above
+		// for the jump/loop group, so anything else lands here.
+		if shouldJump(cpu, instr.Mnemonic) {
+			cpu.IP = uint16(instr.Dst.Value)
+		} else {
+			cpu.IP = fallthroughIP
+		}
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("Usage: simulator <FILENAME>")
+		os.Exit(1)
+	}
+	inputFilename := os.Args[1]
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		panic(err)
+	}
+
+	inputData, err := io.ReadAll(inputFile)
+	if err != nil {
+		panic(err)
+	}
+
+	cpu := NewCPU()
+	cpu.LoadProgram(inputData)
+
+	err = cpu.Run(func(event TraceEvent) {
+		for name, delta := range event.RegisterDeltas {
+			fmt.Printf(
+				"%s 0x%x -> 0x%x ; ip:0x%x\n",
+				name, delta.Before, delta.After, event.IP,
+			)
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("\nFinal registers:")
+	for _, name := range []string{"ax", "bx", "cx", "dx", "sp", "bp", "si", "di"} {
+		fmt.Printf("      %s: 0x%04x (%d)\n", name, cpu.Registers[name], cpu.Registers[name])
+	}
+	fmt.Printf(
+		"   flags: ZF=%t SF=%t OF=%t CF=%t PF=%t AF=%t\n",
+		cpu.Flags.ZF, cpu.Flags.SF, cpu.Flags.OF, cpu.Flags.CF, cpu.Flags.PF, cpu.Flags.AF,
+	)
+}
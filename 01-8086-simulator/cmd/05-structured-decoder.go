@@ -0,0 +1,714 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ MOV, ADD/SUB/CMP/AND/OR/XOR, INC/DEC/PUSH/POP, JMP/CALL/RET and
+ conditional jumps/loop - the same subset 03-arithmetic-and-jump-decoder.go
+ decodes (TEST/NOT/NEG, the shift/rotate group and the string primitives
+ aren't ported here yet) - decoded into a typed Instruction value instead
+ of going straight to a formatted string. That lets the same decode feed
+ more than one output syntax: a Formatter renders an []Instruction into
+ the listing lines, and this file ships two - NASMFormatter (Intel
+ syntax, the only flavour the earlier listings produced) and ATTFormatter
+ (AT&T/GNU syntax), selected on the CLI with -syntax intel|att.
+
+ Decode([]byte) []string is kept as a thin wrapper around DecodeProgram +
+ NASMFormatter, with the same signature and NASM-syntax output as before.
+ The text differs in places: memory references always carry their
+ displacement (`[bx + si + 0]` for MOD=00), displacements and
+ sign-extended immediates print as signed values, and MOD=00 R/M=110
+ prints as a direct address `[N]`.
+*/
+
+// Mnemonic names an operation independent of how an Operand is rendered.
+type Mnemonic string
+
+const (
+	Mov    Mnemonic = "mov"
+	Add    Mnemonic = "add"
+	Sub    Mnemonic = "sub"
+	Cmp    Mnemonic = "cmp"
+	And    Mnemonic = "and"
+	Or     Mnemonic = "or"
+	Xor    Mnemonic = "xor"
+	Inc    Mnemonic = "inc"
+	Dec    Mnemonic = "dec"
+	Push   Mnemonic = "push"
+	Pop    Mnemonic = "pop"
+	Jmp    Mnemonic = "jmp"
+	Call   Mnemonic = "call"
+	Ret    Mnemonic = "ret"
+	Je     Mnemonic = "je"
+	Jl     Mnemonic = "jl"
+	Jle    Mnemonic = "jle"
+	Jb     Mnemonic = "jb"
+	Jbe    Mnemonic = "jbe"
+	Jp     Mnemonic = "jp"
+	Jo     Mnemonic = "jo"
+	Js     Mnemonic = "js"
+	Jne    Mnemonic = "jne"
+	Jnl    Mnemonic = "jnl"
+	Jg     Mnemonic = "jg"
+	Jnb    Mnemonic = "jnb"
+	Ja     Mnemonic = "ja"
+	Jnp    Mnemonic = "jnp"
+	Jno    Mnemonic = "jno"
+	Jns    Mnemonic = "jns"
+	Loop   Mnemonic = "loop"
+	Loopz  Mnemonic = "loopz"
+	Loopnz Mnemonic = "loopnz"
+	Jcxz   Mnemonic = "jcxz"
+)
+
+// Operand is implemented by every kind of instruction argument the
+// decoder produces. It exists purely to group the concrete operand types
+// under one field type on Instruction.
+type Operand interface {
+	isOperand()
+}
+
+// RegOperand names a register, e.g. ax or al.
+type RegOperand struct {
+	Name string
+	Wide bool
+}
+
+// MemOperand is an effective-address reference: [Base + Index + Disp].
+// Base and/or Index are "" when the addressing mode omits them (e.g.
+// MOD=00 R/M=100 is just [si], so Base is "").
+type MemOperand struct {
+	Base  string
+	Index string
+	Disp  int
+	Wide  bool
+}
+
+// ImmOperand is a literal value, e.g. the 12 in `add cx, 12`.
+type ImmOperand struct {
+	Value int64
+}
+
+// LabelOperand is a jump/loop target, recorded as the absolute byte
+// offset it was decoded to. Formatters are responsible for synthesising
+// a display name (e.g. "label_0") for each distinct address referenced.
+type LabelOperand struct {
+	Address uint16
+}
+
+func (RegOperand) isOperand()   {}
+func (MemOperand) isOperand()   {}
+func (ImmOperand) isOperand()   {}
+func (LabelOperand) isOperand() {}
+
+// Instruction is the decoder's output: everything a formatter or the
+// simulator needs to render or execute one machine instruction.
+type Instruction struct {
+	Op      Mnemonic
+	Dst, Src Operand // Src is nil for unary ops (INC/DEC/PUSH/POP, the jump/loop group); Dst is also nil for RET
+	Size    int      // encoded length in bytes
+	Address uint16   // offset of the first byte in the input
+	Bytes   []byte   // the raw encoded bytes, Address:Address+Size
+}
+
+// Formatter renders a decoded program as listing lines in its own syntax,
+// one instruction (or synthesised label) per entry.
+type Formatter interface {
+	FormatProgram(instructions []Instruction) []string
+}
+
+var structuredRegisterNames map[byte]map[byte]string = map[byte]map[byte]string{
+	0b000: {0: "al", 1: "ax"},
+	0b001: {0: "cl", 1: "cx"},
+	0b010: {0: "dl", 1: "dx"},
+	0b011: {0: "bl", 1: "bx"},
+	0b100: {0: "ah", 1: "sp"},
+	0b101: {0: "ch", 1: "bp"},
+	0b110: {0: "dh", 1: "si"},
+	0b111: {0: "bh", 1: "di"},
+}
+
+var structuredMemoryEquations = map[byte]struct {
+	Base  string
+	Index string
+}{
+	0b000: {"bx", "si"},
+	0b001: {"bx", "di"},
+	0b010: {"bp", "si"},
+	0b011: {"bp", "di"},
+	0b100: {"", "si"},
+	0b101: {"", "di"},
+	0b110: {"bp", ""},
+	0b111: {"bx", ""},
+}
+
+var structuredArithmeticOpExtensions = map[byte]Mnemonic{
+	0b000: Add,
+	0b001: Or,
+	0b100: And,
+	0b101: Sub,
+	0b110: Xor,
+	0b111: Cmp,
+}
+
+// structuredRegisterOpMnemonics maps the OP bits (bits 4-3) of an
+// INC/DEC/PUSH/POP register opcode to its mnemonic.
+var structuredRegisterOpMnemonics = map[byte]Mnemonic{
+	0b00: Inc,
+	0b01: Dec,
+	0b10: Push,
+	0b11: Pop,
+}
+
+var structuredJumpMnemonics = map[byte]Mnemonic{
+	0x74: Je, 0x7C: Jl, 0x7E: Jle, 0x72: Jb, 0x76: Jbe,
+	0x7A: Jp, 0x70: Jo, 0x78: Js, 0x75: Jne, 0x7D: Jnl,
+	0x7F: Jg, 0x73: Jnb, 0x77: Ja, 0x7B: Jnp, 0x71: Jno,
+	0x79: Jns, 0xE2: Loop, 0xE1: Loopz, 0xE0: Loopnz, 0xE3: Jcxz,
+	0xEB: Jmp,
+}
+
+func structuredRegName(reg byte, wideBit byte) string {
+	return structuredRegisterNames[reg][wideBit]
+}
+
+func isJumpOpcode(b byte) bool {
+	_, ok := structuredJumpMnemonics[b]
+	return ok
+}
+
+// DecodeProgram decodes every instruction in input, in order, returning
+// the typed AST for each. It panics on malformed input, same as Decode
+// always has - chunk0-4 in this backlog replaces that with a proper
+// error type.
+func DecodeProgram(input []byte) []Instruction {
+	var instructions []Instruction
+	pointer := 0
+	for pointer < len(input) {
+		if len(input)-pointer == 1 {
+			// RET and the INC/DEC/PUSH/POP register forms are the only
+			// one-byte instructions this decoder supports; every other
+			// opcode reads at least a second byte.
+			nextByte := input[pointer]
+			if nextByte == 0xC3 {
+				instructions = append(instructions, mustDecodeStructuredRet(input, pointer))
+				break
+			}
+			if top5 := nextByte >> 3; top5 >= 0b01000 && top5 <= 0b01011 {
+				instr, err := decodeStructuredRegisterOp(input, pointer)
+				if err != nil {
+					panic(err)
+				}
+				instructions = append(instructions, instr)
+				break
+			}
+			panic(fmt.Errorf("Trailing byte found"))
+		}
+
+		instr, err := decodeStructuredAt(input, pointer)
+		if err != nil {
+			panic(err)
+		}
+		instructions = append(instructions, instr)
+		pointer += instr.Size
+	}
+	return instructions
+}
+
+func decodeStructuredAt(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+
+	switch {
+	case isJumpOpcode(byte1):
+		return decodeStructuredJump(data, pointer)
+	case byte1 == 0xE8:
+		return decodeStructuredCall(data, pointer)
+	case byte1 == 0xC3:
+		return mustDecodeStructuredRet(data, pointer), nil
+	case byte1>>1 == 0b0000010, byte1>>1 == 0b0000110, byte1>>1 == 0b0010010,
+		byte1>>1 == 0b0010110, byte1>>1 == 0b0011010, byte1>>1 == 0b0011110:
+		return decodeStructuredAccumulatorImm(data, pointer)
+	case byte1>>2 == 0b100010, byte1>>2 == 0b000000, byte1>>2 == 0b001010,
+		byte1>>2 == 0b001110, byte1>>2 == 0b001000, byte1>>2 == 0b000010, byte1>>2 == 0b001100:
+		return decodeStructuredRegMem(data, pointer)
+	case byte1>>2 == 0b100000:
+		return decodeStructuredImmToRegMem(data, pointer)
+	case byte1>>4 == 0b1011:
+		return decodeStructuredImmToRegMov(data, pointer)
+	case byte1>>3 >= 0b01000 && byte1>>3 <= 0b01011:
+		return decodeStructuredRegisterOp(data, pointer)
+	default:
+		return Instruction{}, fmt.Errorf("Unknown opcode: %b", byte1)
+	}
+}
+
+func structuredMnemonicForRegMemOpcode(opcode byte) (Mnemonic, error) {
+	switch opcode {
+	case 0b100010:
+		return Mov, nil
+	case 0b000000:
+		return Add, nil
+	case 0b001010:
+		return Sub, nil
+	case 0b001110:
+		return Cmp, nil
+	case 0b001000:
+		return And, nil
+	case 0b000010:
+		return Or, nil
+	case 0b001100:
+		return Xor, nil
+	default:
+		return "", fmt.Errorf("Unexpected reg/mem opcode: %b", opcode)
+	}
+}
+
+// decodeStructuredRegisterOp decodes the one-byte INC/DEC/PUSH/POP
+// register forms, which name their (always 16-bit) register directly in
+// the opcode's low 3 bits instead of via a MOD/REG/R-M byte.
+func decodeStructuredRegisterOp(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	op := (byte1 >> 3) & 0b11
+	reg := byte1 & 0b111
+
+	mnemonic, ok := structuredRegisterOpMnemonics[op]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unexpected register-op opcode: %b", byte1)
+	}
+
+	dst := RegOperand{Name: structuredRegName(reg, 1), Wide: true}
+	return Instruction{
+		Op: mnemonic, Dst: dst,
+		Size: 1, Address: uint16(pointer), Bytes: data[pointer : pointer+1],
+	}, nil
+}
+
+// decodeStructuredCall decodes the near direct CALL (`1110 1000`), the
+// only instruction in this set whose IP-relative displacement is 16 bits
+// rather than 8.
+func decodeStructuredCall(data []byte, pointer int) (Instruction, error) {
+	displacement := int16(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+	target := uint16(pointer + 3 + int(displacement))
+	return Instruction{
+		Op: Call, Dst: LabelOperand{Address: target},
+		Size: 3, Address: uint16(pointer), Bytes: data[pointer : pointer+3],
+	}, nil
+}
+
+// mustDecodeStructuredRet decodes the near RET (`1100 0011`), which takes
+// no operand and can't fail.
+func mustDecodeStructuredRet(data []byte, pointer int) Instruction {
+	return Instruction{
+		Op: Ret, Size: 1, Address: uint16(pointer), Bytes: data[pointer : pointer+1],
+	}
+}
+
+// decodeStructuredRM decodes the operand named by a MOD/R-M pair,
+// returning it plus the number of displacement bytes consumed beyond the
+// two bytes every reg/mem encoding starts with.
+func decodeStructuredRM(data []byte, pointer int, mode byte, rm byte, wideBit byte) (Operand, int) {
+	equation := structuredMemoryEquations[rm]
+	switch mode {
+	case 0b01:
+		disp := int8(data[pointer+2])
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(disp), Wide: wideBit == 1}, 1
+	case 0b10:
+		disp := int16(binary.LittleEndian.Uint16(data[pointer+2 : pointer+4]))
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(disp), Wide: wideBit == 1}, 2
+	case 0b11:
+		return RegOperand{Name: structuredRegName(rm, wideBit), Wide: wideBit == 1}, 0
+	default: // 0b00
+		if rm == 0b110 {
+			// MOD=00 R/M=110 is the direct-address special case, not
+			// "[bp] with no displacement" - the 16-bit address follows
+			// in full, same as encodeMemRM produces it in 08-encoder.go.
+			disp := int16(binary.LittleEndian.Uint16(data[pointer+2 : pointer+4]))
+			return MemOperand{Disp: int(disp), Wide: wideBit == 1}, 2
+		}
+		return MemOperand{Base: equation.Base, Index: equation.Index, Wide: wideBit == 1}, 0
+	}
+}
+
+func decodeStructuredRegMem(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	byte2 := data[pointer+1]
+
+	mnemonic, err := structuredMnemonicForRegMemOpcode(byte1 >> 2)
+	if err != nil {
+		return Instruction{}, err
+	}
+
+	destinationBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	reg := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	regOperand := RegOperand{Name: structuredRegName(reg, wideBit), Wide: wideBit == 1}
+	rmOperand, extra := decodeStructuredRM(data, pointer, mode, rm, wideBit)
+
+	var dst, src Operand
+	if destinationBit == 1 {
+		dst, src = regOperand, rmOperand
+	} else {
+		dst, src = rmOperand, regOperand
+	}
+
+	size := 2 + extra
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: src,
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeStructuredImmToRegMem(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	byte2 := data[pointer+1]
+
+	signExtendBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := structuredArithmeticOpExtensions[opField]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unsupported arithmetic extension: %b", opField)
+	}
+
+	dst, extra := decodeStructuredRM(data, pointer, mode, rm, wideBit)
+	dataOffset := pointer + 2 + extra
+
+	var value int64
+	var dataLen int
+	switch {
+	case wideBit == 1 && signExtendBit == 0:
+		value = int64(binary.LittleEndian.Uint16(data[dataOffset : dataOffset+2]))
+		dataLen = 2
+	case wideBit == 1 && signExtendBit == 1:
+		// Sign-extend the byte to match the 16-bit operand.
+		value = int64(int8(data[dataOffset]))
+		dataLen = 1
+	default:
+		// 8-bit operand: the immediate is just a raw byte, not sign-extended.
+		value = int64(data[dataOffset])
+		dataLen = 1
+	}
+
+	size := 2 + extra + dataLen
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeStructuredAccumulatorImm(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	wideBit := byte1 & 0b1
+
+	var mnemonic Mnemonic
+	switch byte1 >> 1 {
+	case 0b0000010:
+		mnemonic = Add
+	case 0b0000110:
+		mnemonic = Or
+	case 0b0010010:
+		mnemonic = And
+	case 0b0010110:
+		mnemonic = Sub
+	case 0b0011010:
+		mnemonic = Xor
+	case 0b0011110:
+		mnemonic = Cmp
+	default:
+		return Instruction{}, fmt.Errorf("Unexpected accumulator opcode: %b", byte1)
+	}
+
+	dst := RegOperand{Name: structuredRegName(0b000, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	var size int
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+		size = 3
+	} else {
+		value = int64(data[pointer+1])
+		size = 2
+	}
+
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeStructuredImmToRegMov(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	wideBit := (byte1 >> 3) & 0b1
+	reg := byte1 & 0b111
+
+	dst := RegOperand{Name: structuredRegName(reg, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	var size int
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+		size = 3
+	} else {
+		value = int64(data[pointer+1])
+		size = 2
+	}
+
+	return Instruction{
+		Op: Mov, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeStructuredJump(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	mnemonic, ok := structuredJumpMnemonics[byte1]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unexpected jump opcode: %b", byte1)
+	}
+
+	displacement := int8(data[pointer+1])
+	target := uint16(pointer + 2 + int(displacement))
+
+	return Instruction{
+		Op: mnemonic, Dst: LabelOperand{Address: target},
+		Size: 2, Address: uint16(pointer), Bytes: data[pointer : pointer+2],
+	}, nil
+}
+
+// synthesiseLabels assigns a "label_N" name, in order of first reference,
+// to every address a LabelOperand in instructions points at. Both
+// formatters share this so label numbering is identical regardless of
+// output syntax.
+func synthesiseLabels(instructions []Instruction) map[uint16]string {
+	labels := map[uint16]string{}
+	for _, instr := range instructions {
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			if _, seen := labels[target.Address]; !seen {
+				labels[target.Address] = fmt.Sprintf("label_%d", len(labels))
+			}
+		}
+	}
+	return labels
+}
+
+// NASMFormatter renders operands in the NASM/Intel syntax
+// 03-arithmetic-and-jump-decoder.go uses: Intel order (dst, src),
+// `[base + index + disp]` memory references, and an explicit byte/word
+// size prefix when an immediate is written straight to memory. Unlike 03,
+// it always writes the displacement (`+ 0` included) and prints it signed.
+type NASMFormatter struct{}
+
+func (NASMFormatter) formatOperand(op Operand) string {
+	switch o := op.(type) {
+	case RegOperand:
+		return o.Name
+	case ImmOperand:
+		return fmt.Sprintf("%d", o.Value)
+	case MemOperand:
+		switch {
+		case o.Base != "" && o.Index != "":
+			return fmt.Sprintf("[%s + %s + %d]", o.Base, o.Index, o.Disp)
+		case o.Base != "":
+			return fmt.Sprintf("[%s + %d]", o.Base, o.Disp)
+		case o.Index != "":
+			return fmt.Sprintf("[%s + %d]", o.Index, o.Disp)
+		default:
+			return fmt.Sprintf("[%d]", o.Disp)
+		}
+	default:
+		return ""
+	}
+}
+
+func (f NASMFormatter) FormatProgram(instructions []Instruction) []string {
+	labels := synthesiseLabels(instructions)
+	lines := make([]string, 0, len(instructions)+len(labels))
+
+	for _, instr := range instructions {
+		if label, ok := labels[instr.Address]; ok {
+			lines = append(lines, label+":")
+		}
+
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			lines = append(lines, fmt.Sprintf("%s %s", instr.Op, labels[target.Address]))
+			continue
+		}
+
+		if instr.Dst == nil {
+			lines = append(lines, string(instr.Op))
+			continue
+		}
+
+		if instr.Src == nil {
+			lines = append(lines, fmt.Sprintf("%s %s", instr.Op, f.formatOperand(instr.Dst)))
+			continue
+		}
+
+		if memOp, isMem := instr.Dst.(MemOperand); isMem {
+			if imm, isImm := instr.Src.(ImmOperand); isImm {
+				size := "byte"
+				if memOp.Wide {
+					size = "word"
+				}
+				lines = append(lines, fmt.Sprintf(
+					"%s %s %s, %d", instr.Op, size, f.formatOperand(instr.Dst), imm.Value,
+				))
+				continue
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%s %s, %s", instr.Op, f.formatOperand(instr.Dst), f.formatOperand(instr.Src),
+		))
+	}
+
+	return lines
+}
+
+// ATTFormatter renders operands AT&T/GNU style: reversed (src, dst)
+// order, `%reg` registers, `$imm` immediates, `disp(base,index)` memory
+// references, and a b/w mnemonic size suffix instead of NASM's leading
+// byte/word keyword.
+type ATTFormatter struct{}
+
+func (ATTFormatter) sizeSuffix(wide bool) string {
+	if wide {
+		return "w"
+	}
+	return "b"
+}
+
+func (f ATTFormatter) formatOperand(op Operand) string {
+	switch o := op.(type) {
+	case RegOperand:
+		return "%" + o.Name
+	case ImmOperand:
+		return fmt.Sprintf("$%d", o.Value)
+	case MemOperand:
+		switch {
+		case o.Base != "" && o.Index != "":
+			return fmt.Sprintf("%d(%%%s,%%%s)", o.Disp, o.Base, o.Index)
+		case o.Base != "":
+			return fmt.Sprintf("%d(%%%s)", o.Disp, o.Base)
+		case o.Index != "":
+			return fmt.Sprintf("%d(,%%%s)", o.Disp, o.Index)
+		default:
+			return fmt.Sprintf("%d", o.Disp)
+		}
+	default:
+		return ""
+	}
+}
+
+func (f ATTFormatter) FormatProgram(instructions []Instruction) []string {
+	labels := synthesiseLabels(instructions)
+	lines := make([]string, 0, len(instructions)+len(labels))
+
+	for _, instr := range instructions {
+		if label, ok := labels[instr.Address]; ok {
+			lines = append(lines, label+":")
+		}
+
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			lines = append(lines, fmt.Sprintf("%s %s", instr.Op, labels[target.Address]))
+			continue
+		}
+
+		if instr.Dst == nil {
+			lines = append(lines, string(instr.Op))
+			continue
+		}
+
+		if instr.Src == nil {
+			wide := true
+			if reg, ok := instr.Dst.(RegOperand); ok {
+				wide = reg.Wide
+			} else if mem, ok := instr.Dst.(MemOperand); ok {
+				wide = mem.Wide
+			}
+			lines = append(lines, fmt.Sprintf("%s%s %s", instr.Op, f.sizeSuffix(wide), f.formatOperand(instr.Dst)))
+			continue
+		}
+
+		wide := true
+		if reg, ok := instr.Dst.(RegOperand); ok {
+			wide = reg.Wide
+		} else if mem, ok := instr.Dst.(MemOperand); ok {
+			wide = mem.Wide
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%s%s %s, %s",
+			instr.Op, f.sizeSuffix(wide), f.formatOperand(instr.Src), f.formatOperand(instr.Dst),
+		))
+	}
+
+	return lines
+}
+
+// Decode keeps the pre-AST signature: NASM-syntax listing lines, one per
+// instruction plus one per synthesised label.
+func Decode(input []byte) []string {
+	return NASMFormatter{}.FormatProgram(DecodeProgram(input))
+}
+
+func main() {
+	usage := func() {
+		fmt.Println("Usage: decoder [-syntax intel|att] <FILENAME>")
+		os.Exit(1)
+	}
+
+	syntax := "intel"
+	var inputFilename string
+	switch len(os.Args) {
+	case 2:
+		inputFilename = os.Args[1]
+	case 4:
+		if os.Args[1] != "-syntax" {
+			usage()
+		}
+		syntax = os.Args[2]
+		inputFilename = os.Args[3]
+	default:
+		usage()
+	}
+
+	var formatter Formatter
+	switch syntax {
+	case "intel":
+		formatter = NASMFormatter{}
+	case "att":
+		formatter = ATTFormatter{}
+	default:
+		fmt.Printf("Unknown syntax %q, expected intel or att\n", syntax)
+		os.Exit(1)
+	}
+
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		panic(err)
+	}
+
+	inputData, err := io.ReadAll(inputFile)
+	if err != nil {
+		panic(err)
+	}
+
+	if _, ok := formatter.(NASMFormatter); ok {
+		fmt.Println("bits 16\n")
+	}
+	for _, line := range formatter.FormatProgram(DecodeProgram(inputData)) {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,699 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ Same AST and instruction set as 05-structured-decoder.go - MOV,
+ ADD/SUB/CMP/AND/OR/XOR, INC/DEC/PUSH/POP, JMP/CALL/RET and conditional
+ jumps/loop (TEST/NOT/NEG, the shift/rotate group and the string
+ primitives aren't ported here yet) - but decoded incrementally from an
+ io.Reader instead of a slurped []byte. NewDecoder wraps any io.Reader;
+ Next() returns one Instruction at a time and io.EOF once the stream is
+ exhausted.
+
+ Internally the decoder keeps at most 6 bytes buffered - the longest
+ encoding this package understands (e.g. `add word [bx + si + 300], 300`:
+ 2 opcode/modrm bytes + 2 displacement bytes + 2 immediate bytes) - so it
+ never buffers more of the input than the longest single instruction.
+
+ Decode errors carry the stream offset, the raw bytes read so far for
+ the instruction in progress, and which decoding stage failed, instead of
+ panicking. That lets a caller disassembling a real binary (where a
+ uniform opcode table won't cover every byte, e.g. alignment padding)
+ recover: skip a byte, emit `db 0xNN` for it, and keep decoding.
+*/
+
+// Mnemonic, Operand and Instruction mirror the AST introduced in
+// 05-structured-decoder.go - duplicated here since this file is, like
+// every other listing in this package, a self-contained program.
+type Mnemonic string
+
+const (
+	Mov    Mnemonic = "mov"
+	Add    Mnemonic = "add"
+	Sub    Mnemonic = "sub"
+	Cmp    Mnemonic = "cmp"
+	And    Mnemonic = "and"
+	Or     Mnemonic = "or"
+	Xor    Mnemonic = "xor"
+	Inc    Mnemonic = "inc"
+	Dec    Mnemonic = "dec"
+	Push   Mnemonic = "push"
+	Pop    Mnemonic = "pop"
+	Jmp    Mnemonic = "jmp"
+	Call   Mnemonic = "call"
+	Ret    Mnemonic = "ret"
+	Je     Mnemonic = "je"
+	Jl     Mnemonic = "jl"
+	Jle    Mnemonic = "jle"
+	Jb     Mnemonic = "jb"
+	Jbe    Mnemonic = "jbe"
+	Jp     Mnemonic = "jp"
+	Jo     Mnemonic = "jo"
+	Js     Mnemonic = "js"
+	Jne    Mnemonic = "jne"
+	Jnl    Mnemonic = "jnl"
+	Jg     Mnemonic = "jg"
+	Jnb    Mnemonic = "jnb"
+	Ja     Mnemonic = "ja"
+	Jnp    Mnemonic = "jnp"
+	Jno    Mnemonic = "jno"
+	Jns    Mnemonic = "jns"
+	Loop   Mnemonic = "loop"
+	Loopz  Mnemonic = "loopz"
+	Loopnz Mnemonic = "loopnz"
+	Jcxz   Mnemonic = "jcxz"
+)
+
+type Operand interface {
+	isOperand()
+}
+
+type RegOperand struct {
+	Name string
+	Wide bool
+}
+
+type MemOperand struct {
+	Base  string
+	Index string
+	Disp  int
+	Wide  bool
+}
+
+type ImmOperand struct {
+	Value int64
+}
+
+// LabelOperand is a jump/loop target, recorded as the absolute byte
+// offset it was decoded to.
+type LabelOperand struct {
+	Address uint16
+}
+
+func (RegOperand) isOperand()   {}
+func (MemOperand) isOperand()   {}
+func (ImmOperand) isOperand()   {}
+func (LabelOperand) isOperand() {}
+
+// Instruction is the decoder's output for a single machine instruction.
+// Src is nil for unary ops (INC/DEC/PUSH/POP, the jump/loop group); Dst
+// is also nil for RET.
+type Instruction struct {
+	Op       Mnemonic
+	Dst, Src Operand
+	Size     int
+	Address  uint16
+	Bytes    []byte
+}
+
+var streamingRegisterNames map[byte]map[byte]string = map[byte]map[byte]string{
+	0b000: {0: "al", 1: "ax"},
+	0b001: {0: "cl", 1: "cx"},
+	0b010: {0: "dl", 1: "dx"},
+	0b011: {0: "bl", 1: "bx"},
+	0b100: {0: "ah", 1: "sp"},
+	0b101: {0: "ch", 1: "bp"},
+	0b110: {0: "dh", 1: "si"},
+	0b111: {0: "bh", 1: "di"},
+}
+
+var streamingMemoryEquations = map[byte]struct {
+	Base  string
+	Index string
+}{
+	0b000: {"bx", "si"},
+	0b001: {"bx", "di"},
+	0b010: {"bp", "si"},
+	0b011: {"bp", "di"},
+	0b100: {"", "si"},
+	0b101: {"", "di"},
+	0b110: {"bp", ""},
+	0b111: {"bx", ""},
+}
+
+var streamingArithmeticOpExtensions = map[byte]Mnemonic{
+	0b000: Add,
+	0b001: Or,
+	0b100: And,
+	0b101: Sub,
+	0b110: Xor,
+	0b111: Cmp,
+}
+
+// streamingRegisterOpMnemonics maps bits 4-3 (the `oo`) of a `010oorrr`
+// register-direct opcode to its mnemonic - INC/DEC/PUSH/POP all name
+// their (always 16-bit) register in the opcode's low 3 bits rather than
+// via a MOD/REG/R-M byte.
+var streamingRegisterOpMnemonics = map[byte]Mnemonic{
+	0b00: Inc,
+	0b01: Dec,
+	0b10: Push,
+	0b11: Pop,
+}
+
+var streamingJumpMnemonics = map[byte]Mnemonic{
+	0x74: Je, 0x7C: Jl, 0x7E: Jle, 0x72: Jb, 0x76: Jbe,
+	0x7A: Jp, 0x70: Jo, 0x78: Js, 0x75: Jne, 0x7D: Jnl,
+	0x7F: Jg, 0x73: Jnb, 0x77: Ja, 0x7B: Jnp, 0x71: Jno,
+	0x79: Jns, 0xE2: Loop, 0xE1: Loopz, 0xE0: Loopnz, 0xE3: Jcxz,
+	0xEB: Jmp,
+}
+
+// maxInstructionBytes is the longest encoding in this package's
+// instruction set: opcode + modrm + 2 displacement bytes + 2 immediate
+// bytes.
+const maxInstructionBytes = 6
+
+// errShortBuffer means decodeFromBuffer needed more bytes than were
+// available to tell whether the instruction is well-formed. It never
+// escapes this file: Next() fills the buffer to maxInstructionBytes before
+// decoding, so a short buffer only happens once the stream has ended, and
+// Next() reports it as a DecodeError.
+var errShortBuffer = errors.New("short buffer")
+
+// DecodeError reports a byte the decoder couldn't make sense of, with
+// enough context (offset, bytes read so far, and the stage that gave up)
+// for a caller to recover and keep decoding the rest of the stream.
+type DecodeError struct {
+	Offset int
+	Bytes  []byte
+	State  string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf(
+		"decode error at offset %d (%s): %v (bytes so far: % x)",
+		e.Offset, e.State, e.Err, e.Bytes,
+	)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder reads 8086 instructions one at a time from an io.Reader.
+type Decoder struct {
+	r      io.Reader
+	buf    []byte
+	offset int
+	eof    bool
+}
+
+// NewDecoder wraps r for incremental instruction-at-a-time decoding.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// fill tops the internal buffer up to `want` bytes, stopping early if the
+// underlying reader reaches EOF.
+func (d *Decoder) fill(want int) error {
+	for len(d.buf) < want && !d.eof {
+		chunk := make([]byte, want-len(d.buf))
+		n, err := d.r.Read(chunk)
+		if n > 0 {
+			d.buf = append(d.buf, chunk[:n]...)
+		}
+		if err != nil {
+			if err == io.EOF {
+				d.eof = true
+				return nil
+			}
+			return err
+		}
+		if n == 0 {
+			// A reader returning (0, nil) repeatedly would spin forever;
+			// treat it the same as EOF rather than looping indefinitely.
+			d.eof = true
+			return nil
+		}
+	}
+	return nil
+}
+
+// Next decodes and returns the instruction at the current stream
+// position, advancing past it. It returns io.EOF once every byte has
+// been consumed, or a *DecodeError if the next byte doesn't start a
+// known encoding or the stream ends mid-instruction.
+func (d *Decoder) Next() (Instruction, error) {
+	if err := d.fill(maxInstructionBytes); err != nil {
+		return Instruction{}, err
+	}
+
+	if len(d.buf) == 0 {
+		return Instruction{}, io.EOF
+	}
+
+	instr, err := decodeFromBuffer(d.buf, d.offset)
+	if err != nil {
+		state := "opcode-dispatch"
+		if errors.Is(err, errShortBuffer) {
+			state = "truncated-instruction"
+		}
+		return Instruction{}, &DecodeError{
+			Offset: d.offset,
+			Bytes:  append([]byte(nil), d.buf...),
+			State:  state,
+			Err:    err,
+		}
+	}
+
+	instr.Address = uint16(d.offset)
+	instr.Bytes = append([]byte(nil), d.buf[:instr.Size]...)
+	d.buf = d.buf[instr.Size:]
+	d.offset += instr.Size
+	return instr, nil
+}
+
+// SkipByte consumes exactly one byte from the stream without attempting
+// to decode it, for callers recovering from a DecodeError (e.g. to emit
+// `db 0xNN` for alignment padding and resume decoding after it).
+func (d *Decoder) SkipByte() (byte, error) {
+	if err := d.fill(1); err != nil {
+		return 0, err
+	}
+	if len(d.buf) == 0 {
+		return 0, io.EOF
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	d.offset++
+	return b, nil
+}
+
+func decodeFromBuffer(buf []byte, offset int) (Instruction, error) {
+	byte1 := buf[0]
+
+	switch {
+	case isStreamingJumpOpcode(byte1):
+		return decodeStreamingJump(buf, offset)
+	case byte1 == 0xE8:
+		return decodeStreamingCall(buf, offset)
+	case byte1 == 0xC3:
+		return decodeStreamingRet(buf)
+	case byte1>>1 == 0b0000010, byte1>>1 == 0b0000110, byte1>>1 == 0b0010010,
+		byte1>>1 == 0b0010110, byte1>>1 == 0b0011010, byte1>>1 == 0b0011110:
+		return decodeStreamingAccumulatorImm(buf)
+	case byte1>>2 == 0b100010, byte1>>2 == 0b000000, byte1>>2 == 0b000010,
+		byte1>>2 == 0b001000, byte1>>2 == 0b001010, byte1>>2 == 0b001100, byte1>>2 == 0b001110:
+		return decodeStreamingRegMem(buf)
+	case byte1>>2 == 0b100000:
+		return decodeStreamingImmToRegMem(buf)
+	case byte1>>4 == 0b1011:
+		return decodeStreamingImmToRegMov(buf)
+	case byte1>>3 >= 0b01000 && byte1>>3 <= 0b01011:
+		return decodeStreamingRegisterOp(buf)
+	default:
+		return Instruction{}, fmt.Errorf("unknown opcode: %b", byte1)
+	}
+}
+
+func isStreamingJumpOpcode(b byte) bool {
+	_, ok := streamingJumpMnemonics[b]
+	return ok
+}
+
+func streamingRegName(reg byte, wideBit byte) string {
+	return streamingRegisterNames[reg][wideBit]
+}
+
+func mnemonicForStreamingRegMemOpcode(opcode byte) (Mnemonic, error) {
+	switch opcode {
+	case 0b100010:
+		return Mov, nil
+	case 0b000000:
+		return Add, nil
+	case 0b000010:
+		return Or, nil
+	case 0b001000:
+		return And, nil
+	case 0b001010:
+		return Sub, nil
+	case 0b001100:
+		return Xor, nil
+	case 0b001110:
+		return Cmp, nil
+	default:
+		return "", fmt.Errorf("unexpected reg/mem opcode: %b", opcode)
+	}
+}
+
+// dispLenForMode returns how many displacement bytes a MOD field adds
+// beyond the two bytes every reg/mem encoding starts with. MOD=00 adds
+// none, except the direct-address special case (R/M=110), which is the
+// one MOD=00 form that still carries a 16-bit displacement.
+func dispLenForMode(mode byte, rm byte) int {
+	switch mode {
+	case 0b01:
+		return 1
+	case 0b10:
+		return 2
+	case 0b11:
+		return 0
+	default: // 0b00
+		if rm == 0b110 {
+			return 2
+		}
+		return 0
+	}
+}
+
+func decodeStreamingRM(buf []byte, mode byte, rm byte, wideBit byte) Operand {
+	equation := streamingMemoryEquations[rm]
+	switch mode {
+	case 0b01:
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(int8(buf[2])), Wide: wideBit == 1}
+	case 0b10:
+		disp := int16(binary.LittleEndian.Uint16(buf[2:4]))
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(disp), Wide: wideBit == 1}
+	case 0b11:
+		return RegOperand{Name: streamingRegName(rm, wideBit), Wide: wideBit == 1}
+	default: // 0b00
+		if rm == 0b110 {
+			disp := int16(binary.LittleEndian.Uint16(buf[2:4]))
+			return MemOperand{Disp: int(disp), Wide: wideBit == 1}
+		}
+		return MemOperand{Base: equation.Base, Index: equation.Index, Wide: wideBit == 1}
+	}
+}
+
+func decodeStreamingRegMem(buf []byte) (Instruction, error) {
+	if len(buf) < 2 {
+		return Instruction{}, errShortBuffer
+	}
+	byte1, byte2 := buf[0], buf[1]
+
+	mnemonic, err := mnemonicForStreamingRegMemOpcode(byte1 >> 2)
+	if err != nil {
+		return Instruction{}, err
+	}
+
+	destinationBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	reg := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	size := 2 + dispLenForMode(mode, rm)
+	if len(buf) < size {
+		return Instruction{}, errShortBuffer
+	}
+
+	regOperand := RegOperand{Name: streamingRegName(reg, wideBit), Wide: wideBit == 1}
+	rmOperand := decodeStreamingRM(buf, mode, rm, wideBit)
+
+	var dst, src Operand
+	if destinationBit == 1 {
+		dst, src = regOperand, rmOperand
+	} else {
+		dst, src = rmOperand, regOperand
+	}
+
+	return Instruction{Op: mnemonic, Dst: dst, Src: src, Size: size}, nil
+}
+
+func decodeStreamingImmToRegMem(buf []byte) (Instruction, error) {
+	if len(buf) < 2 {
+		return Instruction{}, errShortBuffer
+	}
+	byte1, byte2 := buf[0], buf[1]
+
+	signExtendBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := streamingArithmeticOpExtensions[opField]
+	if !ok {
+		return Instruction{}, fmt.Errorf("unsupported arithmetic extension: %b", opField)
+	}
+
+	headerLen := 2 + dispLenForMode(mode, rm)
+	dataLen := 1
+	if wideBit == 1 && signExtendBit == 0 {
+		dataLen = 2
+	}
+	size := headerLen + dataLen
+	if len(buf) < size {
+		return Instruction{}, errShortBuffer
+	}
+
+	dst := decodeStreamingRM(buf, mode, rm, wideBit)
+
+	var value int64
+	switch {
+	case dataLen == 2:
+		value = int64(binary.LittleEndian.Uint16(buf[headerLen : headerLen+2]))
+	case wideBit == 1:
+		// Sign-extend the byte to match the 16-bit operand.
+		value = int64(int8(buf[headerLen]))
+	default:
+		// 8-bit operand: the immediate is just a raw byte, not sign-extended.
+		value = int64(buf[headerLen])
+	}
+
+	return Instruction{Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value}, Size: size}, nil
+}
+
+func decodeStreamingAccumulatorImm(buf []byte) (Instruction, error) {
+	byte1 := buf[0]
+	wideBit := byte1 & 0b1
+
+	var mnemonic Mnemonic
+	switch byte1 >> 1 {
+	case 0b0000010:
+		mnemonic = Add
+	case 0b0000110:
+		mnemonic = Or
+	case 0b0010010:
+		mnemonic = And
+	case 0b0010110:
+		mnemonic = Sub
+	case 0b0011010:
+		mnemonic = Xor
+	case 0b0011110:
+		mnemonic = Cmp
+	default:
+		return Instruction{}, fmt.Errorf("unexpected accumulator opcode: %b", byte1)
+	}
+
+	size := 2
+	if wideBit == 1 {
+		size = 3
+	}
+	if len(buf) < size {
+		return Instruction{}, errShortBuffer
+	}
+
+	dst := RegOperand{Name: streamingRegName(0b000, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(buf[1:3]))
+	} else {
+		value = int64(buf[1])
+	}
+
+	return Instruction{Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value}, Size: size}, nil
+}
+
+func decodeStreamingImmToRegMov(buf []byte) (Instruction, error) {
+	byte1 := buf[0]
+	wideBit := (byte1 >> 3) & 0b1
+	reg := byte1 & 0b111
+
+	size := 2
+	if wideBit == 1 {
+		size = 3
+	}
+	if len(buf) < size {
+		return Instruction{}, errShortBuffer
+	}
+
+	dst := RegOperand{Name: streamingRegName(reg, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(buf[1:3]))
+	} else {
+		value = int64(buf[1])
+	}
+
+	return Instruction{Op: Mov, Dst: dst, Src: ImmOperand{Value: value}, Size: size}, nil
+}
+
+func decodeStreamingJump(buf []byte, offset int) (Instruction, error) {
+	if len(buf) < 2 {
+		return Instruction{}, errShortBuffer
+	}
+	byte1 := buf[0]
+	mnemonic, ok := streamingJumpMnemonics[byte1]
+	if !ok {
+		return Instruction{}, fmt.Errorf("unexpected jump opcode: %b", byte1)
+	}
+
+	displacement := int8(buf[1])
+	target := uint16(offset + 2 + int(displacement))
+
+	return Instruction{Op: mnemonic, Dst: LabelOperand{Address: target}, Size: 2}, nil
+}
+
+// decodeStreamingRegisterOp decodes the one-byte INC/DEC/PUSH/POP
+// register forms (`010oorrr`), which name their (always 16-bit) register
+// directly in the opcode's low 3 bits instead of via a MOD/REG/R-M byte.
+func decodeStreamingRegisterOp(buf []byte) (Instruction, error) {
+	byte1 := buf[0]
+	op := (byte1 >> 3) & 0b11
+	reg := byte1 & 0b111
+
+	mnemonic, ok := streamingRegisterOpMnemonics[op]
+	if !ok {
+		return Instruction{}, fmt.Errorf("unexpected register-op opcode: %b", byte1)
+	}
+
+	dst := RegOperand{Name: streamingRegName(reg, 1), Wide: true}
+	return Instruction{Op: mnemonic, Dst: dst, Size: 1}, nil
+}
+
+// decodeStreamingCall decodes the near direct CALL (`1110 1000`), the
+// only instruction in this set whose IP-relative displacement is 16 bits
+// rather than 8.
+func decodeStreamingCall(buf []byte, offset int) (Instruction, error) {
+	if len(buf) < 3 {
+		return Instruction{}, errShortBuffer
+	}
+	displacement := int16(binary.LittleEndian.Uint16(buf[1:3]))
+	target := uint16(offset + 3 + int(displacement))
+	return Instruction{Op: Call, Dst: LabelOperand{Address: target}, Size: 3}, nil
+}
+
+// decodeStreamingRet decodes the near RET (`1100 0011`), which takes no
+// operand at all.
+func decodeStreamingRet(buf []byte) (Instruction, error) {
+	return Instruction{Op: Ret, Size: 1}, nil
+}
+
+// formatOperand renders an Operand the way NASM would, mirroring
+// 05-structured-decoder.go's NASMFormatter.
+func formatOperand(op Operand) string {
+	switch o := op.(type) {
+	case RegOperand:
+		return o.Name
+	case ImmOperand:
+		return fmt.Sprintf("%d", o.Value)
+	case MemOperand:
+		switch {
+		case o.Base != "" && o.Index != "":
+			return fmt.Sprintf("[%s + %s + %d]", o.Base, o.Index, o.Disp)
+		case o.Base != "":
+			return fmt.Sprintf("[%s + %d]", o.Base, o.Disp)
+		case o.Index != "":
+			return fmt.Sprintf("[%s + %d]", o.Index, o.Disp)
+		default:
+			return fmt.Sprintf("[%d]", o.Disp)
+		}
+	default:
+		return ""
+	}
+}
+
+// formatInstruction renders a decoded Instruction as a NASM source line.
+// Jump/loop targets print as the `label_0xN` name main() defines for
+// that address, rather than the struct itself.
+func formatInstruction(instr Instruction) string {
+	if target, ok := instr.Dst.(LabelOperand); ok {
+		return fmt.Sprintf("%s label_0x%x", instr.Op, target.Address)
+	}
+
+	if instr.Dst == nil {
+		return string(instr.Op)
+	}
+
+	if instr.Src == nil {
+		return fmt.Sprintf("%s %s", instr.Op, formatOperand(instr.Dst))
+	}
+
+	if memOp, isMem := instr.Dst.(MemOperand); isMem {
+		if imm, isImm := instr.Src.(ImmOperand); isImm {
+			size := "byte"
+			if memOp.Wide {
+				size = "word"
+			}
+			return fmt.Sprintf("%s %s %s, %d", instr.Op, size, formatOperand(instr.Dst), imm.Value)
+		}
+	}
+
+	return fmt.Sprintf("%s %s, %s", instr.Op, formatOperand(instr.Dst), formatOperand(instr.Src))
+}
+
+// streamingLine is one decoded item - either a successfully decoded
+// instruction or a byte recovered from a decode error - tagged with the
+// stream offset it started at, so label definitions can be lined up with
+// the instruction they target once every line has been read.
+type streamingLine struct {
+	Address uint16
+	IsDB    bool
+	DBByte  byte
+	Instr   Instruction
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("Usage: decoder <FILENAME>")
+		os.Exit(1)
+	}
+
+	inputFile, err := os.Open(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	defer inputFile.Close()
+
+	decoder := NewDecoder(inputFile)
+
+	var lines []streamingLine
+	labelTargets := map[uint16]bool{}
+	for {
+		instr, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var decodeErr *DecodeError
+			if errors.As(err, &decodeErr) {
+				raw, skipErr := decoder.SkipByte()
+				if skipErr != nil {
+					panic(skipErr)
+				}
+				lines = append(lines, streamingLine{Address: uint16(decodeErr.Offset), IsDB: true, DBByte: raw})
+				continue
+			}
+			panic(err)
+		}
+
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			labelTargets[target.Address] = true
+		}
+		lines = append(lines, streamingLine{Address: instr.Address, Instr: instr})
+	}
+
+	fmt.Println("bits 16\n")
+	for _, line := range lines {
+		if labelTargets[line.Address] {
+			fmt.Printf("label_0x%x:\n", line.Address)
+		}
+		if line.IsDB {
+			fmt.Printf("db 0x%02X\n", line.DBByte)
+			continue
+		}
+		fmt.Println(formatInstruction(line.Instr))
+	}
+}
@@ -0,0 +1,620 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+ Same AST and instruction set as 05-structured-decoder.go (MOV,
+ ADD/SUB/CMP/AND/OR/XOR, INC/DEC/PUSH/POP, JMP/CALL/RET and conditional
+ jumps/loop), with one more Formatter: a listing
+ mode (`-listing` on the CLI) that prefixes each instruction with its file
+ offset and raw encoded bytes, e.g.:
+
+   0007  89 D8            mov ax, bx
+
+ so the output can be compared side-by-side against `ndisasm -b16` while
+ learning the encoding. This only needed Address and Bytes threaded onto
+ Instruction, which the AST refactor already carries.
+*/
+
+type Mnemonic string
+
+const (
+	Mov    Mnemonic = "mov"
+	Add    Mnemonic = "add"
+	Sub    Mnemonic = "sub"
+	Cmp    Mnemonic = "cmp"
+	And    Mnemonic = "and"
+	Or     Mnemonic = "or"
+	Xor    Mnemonic = "xor"
+	Inc    Mnemonic = "inc"
+	Dec    Mnemonic = "dec"
+	Push   Mnemonic = "push"
+	Pop    Mnemonic = "pop"
+	Jmp    Mnemonic = "jmp"
+	Call   Mnemonic = "call"
+	Ret    Mnemonic = "ret"
+	Je     Mnemonic = "je"
+	Jl     Mnemonic = "jl"
+	Jle    Mnemonic = "jle"
+	Jb     Mnemonic = "jb"
+	Jbe    Mnemonic = "jbe"
+	Jp     Mnemonic = "jp"
+	Jo     Mnemonic = "jo"
+	Js     Mnemonic = "js"
+	Jne    Mnemonic = "jne"
+	Jnl    Mnemonic = "jnl"
+	Jg     Mnemonic = "jg"
+	Jnb    Mnemonic = "jnb"
+	Ja     Mnemonic = "ja"
+	Jnp    Mnemonic = "jnp"
+	Jno    Mnemonic = "jno"
+	Jns    Mnemonic = "jns"
+	Loop   Mnemonic = "loop"
+	Loopz  Mnemonic = "loopz"
+	Loopnz Mnemonic = "loopnz"
+	Jcxz   Mnemonic = "jcxz"
+)
+
+type Operand interface {
+	isOperand()
+}
+
+type RegOperand struct {
+	Name string
+	Wide bool
+}
+
+type MemOperand struct {
+	Base  string
+	Index string
+	Disp  int
+	Wide  bool
+}
+
+type ImmOperand struct {
+	Value int64
+}
+
+type LabelOperand struct {
+	Address uint16
+}
+
+func (RegOperand) isOperand()   {}
+func (MemOperand) isOperand()   {}
+func (ImmOperand) isOperand()   {}
+func (LabelOperand) isOperand() {}
+
+type Instruction struct {
+	Op       Mnemonic
+	Dst, Src Operand
+	Size     int
+	Address  uint16
+	Bytes    []byte
+}
+
+type Formatter interface {
+	FormatProgram(instructions []Instruction) []string
+}
+
+var listingRegisterNames map[byte]map[byte]string = map[byte]map[byte]string{
+	0b000: {0: "al", 1: "ax"},
+	0b001: {0: "cl", 1: "cx"},
+	0b010: {0: "dl", 1: "dx"},
+	0b011: {0: "bl", 1: "bx"},
+	0b100: {0: "ah", 1: "sp"},
+	0b101: {0: "ch", 1: "bp"},
+	0b110: {0: "dh", 1: "si"},
+	0b111: {0: "bh", 1: "di"},
+}
+
+var listingMemoryEquations = map[byte]struct {
+	Base  string
+	Index string
+}{
+	0b000: {"bx", "si"},
+	0b001: {"bx", "di"},
+	0b010: {"bp", "si"},
+	0b011: {"bp", "di"},
+	0b100: {"", "si"},
+	0b101: {"", "di"},
+	0b110: {"bp", ""},
+	0b111: {"bx", ""},
+}
+
+var listingArithmeticOpExtensions = map[byte]Mnemonic{
+	0b000: Add,
+	0b001: Or,
+	0b100: And,
+	0b101: Sub,
+	0b110: Xor,
+	0b111: Cmp,
+}
+
+// listingRegisterOpMnemonics maps the OP bits (bits 4-3) of an
+// INC/DEC/PUSH/POP register opcode to its mnemonic.
+var listingRegisterOpMnemonics = map[byte]Mnemonic{
+	0b00: Inc,
+	0b01: Dec,
+	0b10: Push,
+	0b11: Pop,
+}
+
+var listingJumpMnemonics = map[byte]Mnemonic{
+	0x74: Je, 0x7C: Jl, 0x7E: Jle, 0x72: Jb, 0x76: Jbe,
+	0x7A: Jp, 0x70: Jo, 0x78: Js, 0x75: Jne, 0x7D: Jnl,
+	0x7F: Jg, 0x73: Jnb, 0x77: Ja, 0x7B: Jnp, 0x71: Jno,
+	0x79: Jns, 0xE2: Loop, 0xE1: Loopz, 0xE0: Loopnz, 0xE3: Jcxz,
+	0xEB: Jmp,
+}
+
+func listingRegName(reg byte, wideBit byte) string {
+	return listingRegisterNames[reg][wideBit]
+}
+
+func isListingJumpOpcode(b byte) bool {
+	_, ok := listingJumpMnemonics[b]
+	return ok
+}
+
+func DecodeProgram(input []byte) []Instruction {
+	var instructions []Instruction
+	pointer := 0
+	for pointer < len(input) {
+		if len(input)-pointer == 1 {
+			// RET and the INC/DEC/PUSH/POP register forms are the only
+			// one-byte instructions this decoder supports; every other
+			// opcode reads at least a second byte.
+			nextByte := input[pointer]
+			if nextByte == 0xC3 {
+				instructions = append(instructions, mustDecodeListingRet(input, pointer))
+				break
+			}
+			if top5 := nextByte >> 3; top5 >= 0b01000 && top5 <= 0b01011 {
+				instr, err := decodeListingRegisterOp(input, pointer)
+				if err != nil {
+					panic(err)
+				}
+				instructions = append(instructions, instr)
+				break
+			}
+			panic(fmt.Errorf("Trailing byte found"))
+		}
+
+		instr, err := decodeListingAt(input, pointer)
+		if err != nil {
+			panic(err)
+		}
+		instructions = append(instructions, instr)
+		pointer += instr.Size
+	}
+	return instructions
+}
+
+func decodeListingAt(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+
+	switch {
+	case isListingJumpOpcode(byte1):
+		return decodeListingJump(data, pointer)
+	case byte1 == 0xE8:
+		return decodeListingCall(data, pointer)
+	case byte1 == 0xC3:
+		return mustDecodeListingRet(data, pointer), nil
+	case byte1>>1 == 0b0000010, byte1>>1 == 0b0000110, byte1>>1 == 0b0010010,
+		byte1>>1 == 0b0010110, byte1>>1 == 0b0011010, byte1>>1 == 0b0011110:
+		return decodeListingAccumulatorImm(data, pointer)
+	case byte1>>2 == 0b100010, byte1>>2 == 0b000000, byte1>>2 == 0b001010,
+		byte1>>2 == 0b001110, byte1>>2 == 0b001000, byte1>>2 == 0b000010, byte1>>2 == 0b001100:
+		return decodeListingRegMem(data, pointer)
+	case byte1>>2 == 0b100000:
+		return decodeListingImmToRegMem(data, pointer)
+	case byte1>>4 == 0b1011:
+		return decodeListingImmToRegMov(data, pointer)
+	case byte1>>3 >= 0b01000 && byte1>>3 <= 0b01011:
+		return decodeListingRegisterOp(data, pointer)
+	default:
+		return Instruction{}, fmt.Errorf("Unknown opcode: %b", byte1)
+	}
+}
+
+func mnemonicForListingRegMemOpcode(opcode byte) (Mnemonic, error) {
+	switch opcode {
+	case 0b100010:
+		return Mov, nil
+	case 0b000000:
+		return Add, nil
+	case 0b001010:
+		return Sub, nil
+	case 0b001110:
+		return Cmp, nil
+	case 0b001000:
+		return And, nil
+	case 0b000010:
+		return Or, nil
+	case 0b001100:
+		return Xor, nil
+	default:
+		return "", fmt.Errorf("Unexpected reg/mem opcode: %b", opcode)
+	}
+}
+
+// decodeListingRegisterOp decodes the one-byte INC/DEC/PUSH/POP register
+// forms, which name their (always 16-bit) register directly in the
+// opcode's low 3 bits instead of via a MOD/REG/R-M byte.
+func decodeListingRegisterOp(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	op := (byte1 >> 3) & 0b11
+	reg := byte1 & 0b111
+
+	mnemonic, ok := listingRegisterOpMnemonics[op]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unexpected register-op opcode: %b", byte1)
+	}
+
+	dst := RegOperand{Name: listingRegName(reg, 1), Wide: true}
+	return Instruction{
+		Op: mnemonic, Dst: dst,
+		Size: 1, Address: uint16(pointer), Bytes: data[pointer : pointer+1],
+	}, nil
+}
+
+// decodeListingCall decodes the near direct CALL (`1110 1000`), the only
+// instruction in this set whose IP-relative displacement is 16 bits
+// rather than 8.
+func decodeListingCall(data []byte, pointer int) (Instruction, error) {
+	displacement := int16(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+	target := uint16(pointer + 3 + int(displacement))
+	return Instruction{
+		Op: Call, Dst: LabelOperand{Address: target},
+		Size: 3, Address: uint16(pointer), Bytes: data[pointer : pointer+3],
+	}, nil
+}
+
+// mustDecodeListingRet decodes the near RET (`1100 0011`), which takes no
+// operand and can't fail.
+func mustDecodeListingRet(data []byte, pointer int) Instruction {
+	return Instruction{
+		Op: Ret, Size: 1, Address: uint16(pointer), Bytes: data[pointer : pointer+1],
+	}
+}
+
+func decodeListingRM(data []byte, pointer int, mode byte, rm byte, wideBit byte) (Operand, int) {
+	equation := listingMemoryEquations[rm]
+	switch mode {
+	case 0b01:
+		disp := int8(data[pointer+2])
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(disp), Wide: wideBit == 1}, 1
+	case 0b10:
+		disp := int16(binary.LittleEndian.Uint16(data[pointer+2 : pointer+4]))
+		return MemOperand{Base: equation.Base, Index: equation.Index, Disp: int(disp), Wide: wideBit == 1}, 2
+	case 0b11:
+		return RegOperand{Name: listingRegName(rm, wideBit), Wide: wideBit == 1}, 0
+	default: // 0b00
+		if rm == 0b110 {
+			// MOD=00 R/M=110 is the direct-address special case, not
+			// "[bp] with no displacement" - the 16-bit address follows
+			// in full, same as encodeMemRM produces it in 08-encoder.go.
+			disp := int16(binary.LittleEndian.Uint16(data[pointer+2 : pointer+4]))
+			return MemOperand{Disp: int(disp), Wide: wideBit == 1}, 2
+		}
+		return MemOperand{Base: equation.Base, Index: equation.Index, Wide: wideBit == 1}, 0
+	}
+}
+
+func decodeListingRegMem(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	byte2 := data[pointer+1]
+
+	mnemonic, err := mnemonicForListingRegMemOpcode(byte1 >> 2)
+	if err != nil {
+		return Instruction{}, err
+	}
+
+	destinationBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	reg := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	regOperand := RegOperand{Name: listingRegName(reg, wideBit), Wide: wideBit == 1}
+	rmOperand, extra := decodeListingRM(data, pointer, mode, rm, wideBit)
+
+	var dst, src Operand
+	if destinationBit == 1 {
+		dst, src = regOperand, rmOperand
+	} else {
+		dst, src = rmOperand, regOperand
+	}
+
+	size := 2 + extra
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: src,
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeListingImmToRegMem(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	byte2 := data[pointer+1]
+
+	signExtendBit := (byte1 >> 1) & 0b1
+	wideBit := byte1 & 0b1
+	mode := (byte2 >> 6) & 0b11
+	opField := (byte2 >> 3) & 0b111
+	rm := byte2 & 0b111
+
+	mnemonic, ok := listingArithmeticOpExtensions[opField]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unsupported arithmetic extension: %b", opField)
+	}
+
+	dst, extra := decodeListingRM(data, pointer, mode, rm, wideBit)
+	dataOffset := pointer + 2 + extra
+
+	var value int64
+	var dataLen int
+	switch {
+	case wideBit == 1 && signExtendBit == 0:
+		value = int64(binary.LittleEndian.Uint16(data[dataOffset : dataOffset+2]))
+		dataLen = 2
+	case wideBit == 1 && signExtendBit == 1:
+		// Sign-extend the byte to match the 16-bit operand.
+		value = int64(int8(data[dataOffset]))
+		dataLen = 1
+	default:
+		// 8-bit operand: the immediate is just a raw byte, not sign-extended.
+		value = int64(data[dataOffset])
+		dataLen = 1
+	}
+
+	size := 2 + extra + dataLen
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeListingAccumulatorImm(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	wideBit := byte1 & 0b1
+
+	var mnemonic Mnemonic
+	switch byte1 >> 1 {
+	case 0b0000010:
+		mnemonic = Add
+	case 0b0000110:
+		mnemonic = Or
+	case 0b0010010:
+		mnemonic = And
+	case 0b0010110:
+		mnemonic = Sub
+	case 0b0011010:
+		mnemonic = Xor
+	case 0b0011110:
+		mnemonic = Cmp
+	default:
+		return Instruction{}, fmt.Errorf("Unexpected accumulator opcode: %b", byte1)
+	}
+
+	dst := RegOperand{Name: listingRegName(0b000, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	var size int
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+		size = 3
+	} else {
+		value = int64(data[pointer+1])
+		size = 2
+	}
+
+	return Instruction{
+		Op: mnemonic, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeListingImmToRegMov(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	wideBit := (byte1 >> 3) & 0b1
+	reg := byte1 & 0b111
+
+	dst := RegOperand{Name: listingRegName(reg, wideBit), Wide: wideBit == 1}
+
+	var value int64
+	var size int
+	if wideBit == 1 {
+		value = int64(binary.LittleEndian.Uint16(data[pointer+1 : pointer+3]))
+		size = 3
+	} else {
+		value = int64(data[pointer+1])
+		size = 2
+	}
+
+	return Instruction{
+		Op: Mov, Dst: dst, Src: ImmOperand{Value: value},
+		Size: size, Address: uint16(pointer), Bytes: data[pointer : pointer+size],
+	}, nil
+}
+
+func decodeListingJump(data []byte, pointer int) (Instruction, error) {
+	byte1 := data[pointer]
+	mnemonic, ok := listingJumpMnemonics[byte1]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Unexpected jump opcode: %b", byte1)
+	}
+
+	displacement := int8(data[pointer+1])
+	target := uint16(pointer + 2 + int(displacement))
+
+	return Instruction{
+		Op: mnemonic, Dst: LabelOperand{Address: target},
+		Size: 2, Address: uint16(pointer), Bytes: data[pointer : pointer+2],
+	}, nil
+}
+
+func synthesiseLabels(instructions []Instruction) map[uint16]string {
+	labels := map[uint16]string{}
+	for _, instr := range instructions {
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			if _, seen := labels[target.Address]; !seen {
+				labels[target.Address] = fmt.Sprintf("label_%d", len(labels))
+			}
+		}
+	}
+	return labels
+}
+
+func formatOperand(op Operand) string {
+	switch o := op.(type) {
+	case RegOperand:
+		return o.Name
+	case ImmOperand:
+		return fmt.Sprintf("%d", o.Value)
+	case MemOperand:
+		switch {
+		case o.Base != "" && o.Index != "":
+			return fmt.Sprintf("[%s + %s + %d]", o.Base, o.Index, o.Disp)
+		case o.Base != "":
+			return fmt.Sprintf("[%s + %d]", o.Base, o.Disp)
+		case o.Index != "":
+			return fmt.Sprintf("[%s + %d]", o.Index, o.Disp)
+		default:
+			return fmt.Sprintf("[%d]", o.Disp)
+		}
+	default:
+		return ""
+	}
+}
+
+// NASMFormatter renders NASM/Intel-syntax listing lines, resolving jump
+// targets to synthesised `label_N` names.
+type NASMFormatter struct{}
+
+func (NASMFormatter) FormatProgram(instructions []Instruction) []string {
+	labels := synthesiseLabels(instructions)
+	lines := make([]string, 0, len(instructions)+len(labels))
+
+	for _, instr := range instructions {
+		if label, ok := labels[instr.Address]; ok {
+			lines = append(lines, label+":")
+		}
+		lines = append(lines, formatInstructionText(instr, labels))
+	}
+
+	return lines
+}
+
+// formatInstructionText renders one instruction's mnemonic and operands,
+// independent of whatever prefix (a label line, or a listing's offset
+// and raw bytes) a formatter puts in front of it.
+func formatInstructionText(instr Instruction, labels map[uint16]string) string {
+	if target, ok := instr.Dst.(LabelOperand); ok {
+		return fmt.Sprintf("%s %s", instr.Op, labels[target.Address])
+	}
+
+	if instr.Dst == nil {
+		return string(instr.Op)
+	}
+
+	if instr.Src == nil {
+		return fmt.Sprintf("%s %s", instr.Op, formatOperand(instr.Dst))
+	}
+
+	if memOp, isMem := instr.Dst.(MemOperand); isMem {
+		if imm, isImm := instr.Src.(ImmOperand); isImm {
+			size := "byte"
+			if memOp.Wide {
+				size = "word"
+			}
+			return fmt.Sprintf("%s %s %s, %d", instr.Op, size, formatOperand(instr.Dst), imm.Value)
+		}
+	}
+
+	return fmt.Sprintf("%s %s, %s", instr.Op, formatOperand(instr.Dst), formatOperand(instr.Src))
+}
+
+// ListingFormatter prefixes each instruction with its file offset and raw
+// encoded bytes, e.g. `0007  89 D8            mov ax, bx`, for comparing
+// against `ndisasm -b16` byte-for-byte. Jump targets are printed as the
+// absolute offset jumped to rather than a synthesised label, matching
+// what a raw disassembly listing (as opposed to reassemblable NASM
+// source) would show.
+type ListingFormatter struct{}
+
+func (ListingFormatter) FormatProgram(instructions []Instruction) []string {
+	lines := make([]string, 0, len(instructions))
+
+	for _, instr := range instructions {
+		var text string
+		if target, ok := instr.Dst.(LabelOperand); ok {
+			text = fmt.Sprintf("%s 0x%x", instr.Op, target.Address)
+		} else {
+			text = formatInstructionText(instr, nil)
+		}
+
+		hexBytes := make([]string, len(instr.Bytes))
+		for i, b := range instr.Bytes {
+			hexBytes[i] = fmt.Sprintf("%02X", b)
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%04X  %-16s %s", instr.Address, strings.Join(hexBytes, " "), text,
+		))
+	}
+
+	return lines
+}
+
+func Decode(input []byte) []string {
+	return NASMFormatter{}.FormatProgram(DecodeProgram(input))
+}
+
+func main() {
+	usage := func() {
+		fmt.Println("Usage: decoder [-listing] <FILENAME>")
+		os.Exit(1)
+	}
+
+	var listing bool
+	var inputFilename string
+	switch len(os.Args) {
+	case 2:
+		inputFilename = os.Args[1]
+	case 3:
+		if os.Args[1] != "-listing" {
+			usage()
+		}
+		listing = true
+		inputFilename = os.Args[2]
+	default:
+		usage()
+	}
+
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		panic(err)
+	}
+
+	inputData, err := io.ReadAll(inputFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var formatter Formatter = NASMFormatter{}
+	if listing {
+		formatter = ListingFormatter{}
+	} else {
+		fmt.Println("bits 16\n")
+	}
+
+	for _, line := range formatter.FormatProgram(DecodeProgram(inputData)) {
+		fmt.Println(line)
+	}
+}
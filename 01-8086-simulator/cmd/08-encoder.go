@@ -0,0 +1,824 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+ The inverse of 07-listing-decoder.go: parses the subset of NASM syntax
+ this package's decoders emit (reg/mem<->reg and immediate MOV/ADD/SUB/CMP,
+ the conditional jump/loop group, and `label_N:` definitions) and encodes
+ it back to the exact byte stream. Encoding picks the shortest legal form
+ for each instruction - the accumulator short forms for add/sub/cmp ax/al,
+ sign-extended 8-bit immediates where the value allows it, and MOD=00/01/10
+ chosen from the displacement's magnitude.
+
+ Assembling is two passes: the first walks the source building up each
+ label's byte address and encoding every non-jump instruction immediately
+ (their size never depends on a label); the second backpatches the
+ jump/loop instructions, whose 8-bit displacement needs every label
+ address to be known first (a label can be defined after the jump that
+ targets it).
+
+ This only has to round-trip what this package's formatters produce, so
+ unlike a general assembler it doesn't need to handle NASM's full operand
+ grammar, macros, sections, or directives beyond `bits 16`.
+*/
+
+var encoderRegisterIndex = map[string]struct {
+	Index byte
+	Wide  bool
+}{
+	"al": {0b000, false}, "ax": {0b000, true},
+	"cl": {0b001, false}, "cx": {0b001, true},
+	"dl": {0b010, false}, "dx": {0b010, true},
+	"bl": {0b011, false}, "bx": {0b011, true},
+	"ah": {0b100, false}, "sp": {0b100, true},
+	"ch": {0b101, false}, "bp": {0b101, true},
+	"dh": {0b110, false}, "si": {0b110, true},
+	"bh": {0b111, false}, "di": {0b111, true},
+}
+
+// encoderMemRMCode maps a Base/Index register pair back to the R/M
+// field the decoder would have read it from.
+var encoderMemRMCode = map[[2]string]byte{
+	{"bx", "si"}: 0b000,
+	{"bx", "di"}: 0b001,
+	{"bp", "si"}: 0b010,
+	{"bp", "di"}: 0b011,
+	{"", "si"}:   0b100,
+	{"", "di"}:   0b101,
+	{"bp", ""}:   0b110,
+	{"bx", ""}:   0b111,
+}
+
+var encoderArithmeticOpcodeBits = map[string]byte{
+	"mov": 0b100010,
+	"add": 0b000000,
+	"sub": 0b001010,
+	"cmp": 0b001110,
+}
+
+var encoderArithmeticOpExtension = map[string]byte{
+	"add": 0b000,
+	"sub": 0b101,
+	"cmp": 0b111,
+}
+
+// encoderAccumulatorPrefix is the 7-bit prefix (everything but the W bit)
+// of the `add/sub/cmp al/ax, imm` short forms.
+var encoderAccumulatorPrefix = map[string]byte{
+	"add": 0b0000010,
+	"sub": 0b0010110,
+	"cmp": 0b0011110,
+}
+
+var encoderJumpOpcode = map[string]byte{
+	"je": 0x74, "jl": 0x7C, "jle": 0x7E, "jb": 0x72, "jbe": 0x76,
+	"jp": 0x7A, "jo": 0x70, "js": 0x78, "jne": 0x75, "jnl": 0x7D,
+	"jg": 0x7F, "jnb": 0x73, "ja": 0x77, "jnp": 0x7B, "jno": 0x71,
+	"jns": 0x79, "loop": 0xE2, "loopz": 0xE1, "loopnz": 0xE0, "jcxz": 0xE3,
+}
+
+// asmLine is one parsed source line: either a label definition (Label
+// set, everything else empty) or an instruction.
+type asmLine struct {
+	Label    string
+	Mnemonic string
+	Operands []string
+}
+
+// ParseProgram tokenizes NASM-subset source into one asmLine per
+// instruction or label definition. Blank lines, `;` comments and the
+// `bits 16` directive are dropped.
+func ParseProgram(source string) ([]asmLine, error) {
+	var lines []asmLine
+	for lineNumber, rawLine := range strings.Split(source, "\n") {
+		line := rawLine
+		if idx := strings.Index(line, ";"); idx != -1 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(line), "bits") {
+			continue
+		}
+
+		if strings.HasSuffix(line, ":") {
+			lines = append(lines, asmLine{Label: strings.TrimSuffix(line, ":")})
+			continue
+		}
+
+		spaceIdx := strings.IndexAny(line, " \t")
+		if spaceIdx == -1 {
+			return nil, fmt.Errorf("line %d: expected operands after %q", lineNumber+1, line)
+		}
+		mnemonic := strings.ToLower(line[:spaceIdx])
+		rest := strings.TrimSpace(line[spaceIdx+1:])
+
+		operands := splitOperands(rest)
+		for i := range operands {
+			operands[i] = strings.TrimSpace(operands[i])
+		}
+
+		lines = append(lines, asmLine{Mnemonic: mnemonic, Operands: operands})
+	}
+	return lines, nil
+}
+
+// splitOperands splits on top-level commas. None of the operand forms
+// this package emits ever contain a comma inside `[...]`, so a plain
+// split is enough.
+func splitOperands(rest string) []string {
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, ",")
+}
+
+type parsedOperandKind int
+
+const (
+	parsedReg parsedOperandKind = iota
+	parsedImm
+	parsedMem
+)
+
+type parsedOperand struct {
+	Kind  parsedOperandKind
+	Reg   string
+	Value int64
+	Base  string
+	Index string
+	Disp  int
+	// Wide is set directly for registers; for memory operands it's only
+	// known when an explicit `byte`/`word` keyword preceded the `[...]`
+	// (the immediate-to-memory forms always carry one, since there's no
+	// register operand to infer width from).
+	Wide      bool
+	WideKnown bool
+}
+
+func parseOperand(raw string) (parsedOperand, error) {
+	text := strings.TrimSpace(raw)
+
+	wideKnown := false
+	wide := false
+	if strings.HasPrefix(text, "byte ") {
+		wideKnown, wide = true, false
+		text = strings.TrimSpace(strings.TrimPrefix(text, "byte "))
+	} else if strings.HasPrefix(text, "word ") {
+		wideKnown, wide = true, true
+		text = strings.TrimSpace(strings.TrimPrefix(text, "word "))
+	}
+
+	if info, ok := encoderRegisterIndex[text]; ok {
+		return parsedOperand{Kind: parsedReg, Reg: text, Wide: info.Wide, WideKnown: true}, nil
+	}
+
+	if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+		mem, err := parseMemoryExpr(text[1 : len(text)-1])
+		if err != nil {
+			return parsedOperand{}, err
+		}
+		mem.WideKnown = wideKnown
+		mem.Wide = wide
+		return mem, nil
+	}
+
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return parsedOperand{}, fmt.Errorf("not a register, memory reference or immediate: %q", raw)
+	}
+	return parsedOperand{Kind: parsedImm, Value: value}, nil
+}
+
+func parseMemoryExpr(expr string) (parsedOperand, error) {
+	mem := parsedOperand{Kind: parsedMem}
+	for _, term := range strings.Split(expr, "+") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		if _, isReg := encoderRegisterIndex[term]; isReg {
+			switch term {
+			case "bx", "bp":
+				mem.Base = term
+			case "si", "di":
+				mem.Index = term
+			default:
+				return parsedOperand{}, fmt.Errorf("%q can't be used as a memory base/index register", term)
+			}
+			continue
+		}
+		disp, err := strconv.Atoi(term)
+		if err != nil {
+			return parsedOperand{}, fmt.Errorf("invalid memory expression term: %q", term)
+		}
+		mem.Disp = disp
+	}
+	return mem, nil
+}
+
+// Encode assembles source - the NASM subset this package's Decode
+// functions produce - back into its original byte stream.
+func Encode(source string) ([]byte, error) {
+	lines, err := ParseProgram(source)
+	if err != nil {
+		return nil, err
+	}
+
+	type pendingJump struct {
+		Mnemonic string
+		Address  int
+		Target   string
+	}
+
+	var output []byte
+	var pendingJumps []pendingJump
+	labelAddresses := map[string]int{}
+
+	for _, line := range lines {
+		if line.Label != "" {
+			labelAddresses[line.Label] = len(output)
+			continue
+		}
+
+		if _, ok := encoderJumpOpcode[line.Mnemonic]; ok {
+			if len(line.Operands) != 1 {
+				return nil, fmt.Errorf("%s expects exactly one operand (a label), got %d", line.Mnemonic, len(line.Operands))
+			}
+			pendingJumps = append(pendingJumps, pendingJump{
+				Mnemonic: line.Mnemonic,
+				Address:  len(output),
+				Target:   line.Operands[0],
+			})
+			output = append(output, 0, 0)
+			continue
+		}
+
+		encoded, err := encodeInstruction(line.Mnemonic, line.Operands)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, encoded...)
+	}
+
+	for _, jump := range pendingJumps {
+		targetAddr, ok := labelAddresses[jump.Target]
+		if !ok {
+			return nil, fmt.Errorf("undefined label %q", jump.Target)
+		}
+		opcode := encoderJumpOpcode[jump.Mnemonic]
+		displacement := targetAddr - (jump.Address + 2)
+		if displacement < -128 || displacement > 127 {
+			return nil, fmt.Errorf("%s to %q: displacement %d doesn't fit in 8 bits", jump.Mnemonic, jump.Target, displacement)
+		}
+		output[jump.Address] = opcode
+		output[jump.Address+1] = byte(int8(displacement))
+	}
+
+	return output, nil
+}
+
+func encodeInstruction(mnemonic string, rawOperands []string) ([]byte, error) {
+	if len(rawOperands) != 2 {
+		return nil, fmt.Errorf("%s expects two operands, got %d", mnemonic, len(rawOperands))
+	}
+	dst, err := parseOperand(rawOperands[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s destination operand: %w", mnemonic, err)
+	}
+	src, err := parseOperand(rawOperands[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s source operand: %w", mnemonic, err)
+	}
+
+	if mnemonic != "mov" {
+		if dst.Kind == parsedReg && (dst.Reg == "al" || dst.Reg == "ax") && src.Kind == parsedImm {
+			return encodeAccumulatorImmediate(mnemonic, dst, src)
+		}
+	}
+
+	if dst.Kind == parsedMem && src.Kind == parsedImm {
+		if mnemonic == "mov" {
+			return nil, fmt.Errorf("mov <mem>, <imm> isn't part of the decoded subset")
+		}
+		return encodeImmediateToRegMem(mnemonic, dst, src)
+	}
+
+	if dst.Kind == parsedReg && src.Kind == parsedImm {
+		if mnemonic == "mov" {
+			return encodeImmediateToReg(dst, src)
+		}
+		return encodeImmediateToRegMem(mnemonic, dst, src)
+	}
+
+	if (dst.Kind == parsedReg || dst.Kind == parsedMem) && (src.Kind == parsedReg || src.Kind == parsedMem) {
+		return encodeRegMem(mnemonic, dst, src)
+	}
+
+	return nil, fmt.Errorf("%s %s, %s: unsupported operand combination", mnemonic, rawOperands[0], rawOperands[1])
+}
+
+func encodeRegMem(mnemonic string, dst, src parsedOperand) ([]byte, error) {
+	opcodeBits, ok := encoderArithmeticOpcodeBits[mnemonic]
+	if !ok {
+		return nil, fmt.Errorf("unsupported reg/mem mnemonic: %s", mnemonic)
+	}
+
+	var destinationBit byte
+	var regOperand, rmOperand parsedOperand
+	if dst.Kind == parsedReg {
+		destinationBit = 1
+		regOperand, rmOperand = dst, src
+	} else {
+		destinationBit = 0
+		regOperand, rmOperand = src, dst
+	}
+	if regOperand.Kind != parsedReg {
+		return nil, fmt.Errorf("%s requires at least one register operand", mnemonic)
+	}
+
+	wideBit := byte(0)
+	if regOperand.Wide {
+		wideBit = 1
+	}
+
+	byte1 := (opcodeBits << 2) | (destinationBit << 1) | wideBit
+	regField := encoderRegisterIndex[regOperand.Reg].Index
+
+	if rmOperand.Kind == parsedReg {
+		rmField := encoderRegisterIndex[rmOperand.Reg].Index
+		byte2 := (byte(0b11) << 6) | (regField << 3) | rmField
+		return []byte{byte1, byte2}, nil
+	}
+
+	mode, rmField, dispBytes, err := encodeMemRM(rmOperand)
+	if err != nil {
+		return nil, err
+	}
+	byte2 := (mode << 6) | (regField << 3) | rmField
+	return append([]byte{byte1, byte2}, dispBytes...), nil
+}
+
+// encodeMemRM picks MOD from the displacement's magnitude and returns the
+// R/M field plus the displacement bytes (if any) that follow byte 2.
+func encodeMemRM(mem parsedOperand) (mode byte, rmField byte, dispBytes []byte, err error) {
+	if mem.Base == "" && mem.Index == "" {
+		// No base or index register at all: MOD=00/R-M=110 is the direct-
+		// address special case rather than "no displacement" - the 16-bit
+		// address always follows in full, regardless of its magnitude.
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(int16(mem.Disp)))
+		return 0b00, 0b110, buf, nil
+	}
+
+	rmField, ok := encoderMemRMCode[[2]string{mem.Base, mem.Index}]
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("unsupported memory base/index combination: [%s + %s]", mem.Base, mem.Index)
+	}
+
+	// [bp] alone can't use MOD=00 - that bit pattern is reserved for the
+	// direct-address special case above - so it's encoded as a 1-byte
+	// displacement of 0 instead, same as a real assembler would.
+	if rmField == 0b110 && mem.Disp == 0 {
+		return 0b01, rmField, []byte{0}, nil
+	}
+
+	switch {
+	case mem.Disp == 0:
+		return 0b00, rmField, nil, nil
+	case mem.Disp >= -128 && mem.Disp <= 127:
+		return 0b01, rmField, []byte{byte(int8(mem.Disp))}, nil
+	case mem.Disp >= -32768 && mem.Disp <= 32767:
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(int16(mem.Disp)))
+		return 0b10, rmField, buf, nil
+	default:
+		return 0, 0, nil, fmt.Errorf("displacement %d doesn't fit in 16 bits", mem.Disp)
+	}
+}
+
+// encodeImmediateToRegMem handles both forms of the `1000 00sw` immediate
+// arithmetic opcode: MOD=11 addresses a register directly (dst.Kind ==
+// parsedReg), anything else falls through to encodeMemRM for the
+// MOD=00/01/10 memory forms - mirroring the decoders, which read both
+// shapes through the same MOD/REG/R-M byte.
+func encodeImmediateToRegMem(mnemonic string, dst, src parsedOperand) ([]byte, error) {
+	opExtension, ok := encoderArithmeticOpExtension[mnemonic]
+	if !ok {
+		return nil, fmt.Errorf("unsupported immediate-to-reg/mem mnemonic: %s", mnemonic)
+	}
+
+	var mode, rmField byte
+	var dispBytes []byte
+	var wide bool
+	if dst.Kind == parsedReg {
+		info := encoderRegisterIndex[dst.Reg]
+		mode, rmField, wide = 0b11, info.Index, info.Wide
+	} else {
+		if !dst.WideKnown {
+			return nil, fmt.Errorf("%s <mem>, <imm> needs an explicit byte/word size", mnemonic)
+		}
+		var err error
+		mode, rmField, dispBytes, err = encodeMemRM(dst)
+		if err != nil {
+			return nil, err
+		}
+		wide = dst.Wide
+	}
+
+	wideBit := byte(0)
+	if wide {
+		wideBit = 1
+	}
+
+	signExtendBit := byte(0)
+	var dataBytes []byte
+	if wide {
+		if src.Value >= -128 && src.Value <= 127 {
+			signExtendBit = 1
+			dataBytes = []byte{byte(int8(src.Value))}
+		} else {
+			buf := make([]byte, 2)
+			binary.LittleEndian.PutUint16(buf, uint16(int16(src.Value)))
+			dataBytes = buf
+		}
+	} else {
+		dataBytes = []byte{byte(int8(src.Value))}
+	}
+
+	byte1 := byte(0b100000<<2) | (signExtendBit << 1) | wideBit
+	byte2 := (mode << 6) | (opExtension << 3) | rmField
+
+	result := append([]byte{byte1, byte2}, dispBytes...)
+	result = append(result, dataBytes...)
+	return result, nil
+}
+
+func encodeAccumulatorImmediate(mnemonic string, dst, src parsedOperand) ([]byte, error) {
+	prefix, ok := encoderAccumulatorPrefix[mnemonic]
+	if !ok {
+		return nil, fmt.Errorf("unsupported accumulator mnemonic: %s", mnemonic)
+	}
+
+	wideBit := byte(0)
+	if dst.Wide {
+		wideBit = 1
+	}
+	byte1 := (prefix << 1) | wideBit
+
+	if dst.Wide {
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(int16(src.Value)))
+		return append([]byte{byte1}, buf...), nil
+	}
+	return []byte{byte1, byte(src.Value)}, nil
+}
+
+func encodeImmediateToReg(dst, src parsedOperand) ([]byte, error) {
+	info := encoderRegisterIndex[dst.Reg]
+	wideBit := byte(0)
+	if info.Wide {
+		wideBit = 1
+	}
+	byte1 := (byte(0b1011) << 4) | (wideBit << 3) | info.Index
+
+	if info.Wide {
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(int16(src.Value)))
+		return append([]byte{byte1}, buf...), nil
+	}
+	return []byte{byte1, byte(src.Value)}, nil
+}
+
+// verifyJumpMnemonicByOpcode is the decode-direction inverse of
+// encoderJumpOpcode, used only by decodeForVerify below.
+var verifyJumpMnemonicByOpcode = func() map[byte]string {
+	byOpcode := make(map[byte]string, len(encoderJumpOpcode))
+	for mnemonic, opcode := range encoderJumpOpcode {
+		byOpcode[opcode] = mnemonic
+	}
+	return byOpcode
+}()
+
+var verifyRegMemMnemonics = map[byte]string{
+	0b100010: "mov",
+	0b000000: "add",
+	0b001010: "sub",
+	0b001110: "cmp",
+}
+
+var verifyImmToRegMemMnemonics = map[byte]string{
+	0b000: "add",
+	0b101: "sub",
+	0b111: "cmp",
+}
+
+// decodeMemEquation names the operand a MOD/R-M pair refers to, handling
+// the MOD=00/R-M=110 direct-address special case the same way
+// encodeMemRM produces it.
+func decodeMemEquation(data []byte, pointer int, mode, rm byte) (text string, dispLen int) {
+	if mode == 0b00 && rm == 0b110 {
+		disp := int16(binary.LittleEndian.Uint16(data[pointer : pointer+2]))
+		return fmt.Sprintf("[%d]", disp), 2
+	}
+
+	base, index := "", ""
+	for baseIndex, code := range encoderMemRMCode {
+		if code == rm {
+			base, index = baseIndex[0], baseIndex[1]
+		}
+	}
+
+	switch mode {
+	case 0b01:
+		disp := int8(data[pointer])
+		return fmt.Sprintf("[%s + %d]", joinBaseIndex(base, index), disp), 1
+	case 0b10:
+		disp := int16(binary.LittleEndian.Uint16(data[pointer : pointer+2]))
+		return fmt.Sprintf("[%s + %d]", joinBaseIndex(base, index), disp), 2
+	default: // 0b00
+		return fmt.Sprintf("[%s + 0]", joinBaseIndex(base, index)), 0
+	}
+}
+
+func joinBaseIndex(base, index string) string {
+	switch {
+	case base != "" && index != "":
+		return base + " + " + index
+	case base != "":
+		return base
+	default:
+		return index
+	}
+}
+
+func decodeRegName(index byte, wide bool) string {
+	for name, info := range encoderRegisterIndex {
+		if info.Index == index && info.Wide == wide {
+			return name
+		}
+	}
+	return ""
+}
+
+// decodeForVerify decodes exactly the instruction subset Encode supports
+// (mov/add/sub/cmp and the conditional jump/loop group) back into NASM
+// text, addressing each line by the byte offset it started at so
+// jump/loop targets can be rendered consistently.
+//
+// This package has no module and no shared types with
+// 07-listing-decoder.go - every listing in this series is a standalone
+// `go run`-able program - so it can't call that file's real DecodeProgram
+// directly. decodeForVerify exists to make VerifyRoundTrip possible
+// without that dependency, which makes it worth pinning against known-
+// good output directly rather than trusting it to agree with itself:
+// see TestDecodeForVerify in 08-encoder_test.go, which checks its output
+// against hand-verified NASM text, not just round-trip self-consistency.
+func decodeForVerify(data []byte) ([]string, error) {
+	type line struct {
+		Address int
+		Text    string
+	}
+	var lines []line
+	labelTargets := map[int]bool{}
+
+	pointer := 0
+	for pointer < len(data) {
+		start := pointer
+		byte1 := data[pointer]
+
+		if mnemonic, ok := verifyJumpMnemonicByOpcode[byte1]; ok {
+			if pointer+2 > len(data) {
+				return nil, fmt.Errorf("offset %d: truncated jump instruction", pointer)
+			}
+			displacement := int8(data[pointer+1])
+			pointer += 2
+			target := pointer + int(displacement)
+			labelTargets[target] = true
+			lines = append(lines, line{start, fmt.Sprintf("%s label_0x%x", mnemonic, target)})
+			continue
+		}
+
+		if byte1>>4 == 0b1011 {
+			wideBit := (byte1 >> 3) & 0b1
+			reg := byte1 & 0b111
+			dataLen := 1
+			var value uint16
+			if wideBit == 1 {
+				if pointer+3 > len(data) {
+					return nil, fmt.Errorf("offset %d: truncated mov immediate", pointer)
+				}
+				value = binary.LittleEndian.Uint16(data[pointer+1 : pointer+3])
+				dataLen = 2
+			} else {
+				if pointer+2 > len(data) {
+					return nil, fmt.Errorf("offset %d: truncated mov immediate", pointer)
+				}
+				value = uint16(data[pointer+1])
+			}
+			pointer += 1 + dataLen
+			lines = append(lines, line{start, fmt.Sprintf("mov %s, %d", decodeRegName(reg, wideBit == 1), value)})
+			continue
+		}
+
+		if byte1>>1 == 0b0000010 || byte1>>1 == 0b0010110 || byte1>>1 == 0b0011110 {
+			wideBit := byte1 & 0b1
+			mnemonic := map[byte]string{0b0000010: "add", 0b0010110: "sub", 0b0011110: "cmp"}[byte1>>1]
+			dataLen := 1
+			var value uint16
+			if wideBit == 1 {
+				if pointer+3 > len(data) {
+					return nil, fmt.Errorf("offset %d: truncated accumulator immediate", pointer)
+				}
+				value = binary.LittleEndian.Uint16(data[pointer+1 : pointer+3])
+				dataLen = 2
+			} else {
+				if pointer+2 > len(data) {
+					return nil, fmt.Errorf("offset %d: truncated accumulator immediate", pointer)
+				}
+				value = uint16(data[pointer+1])
+			}
+			pointer += 1 + dataLen
+			regName := decodeRegName(0b000, wideBit == 1)
+			lines = append(lines, line{start, fmt.Sprintf("%s %s, %d", mnemonic, regName, value)})
+			continue
+		}
+
+		if byte1>>2 == 0b100000 {
+			if pointer+2 > len(data) {
+				return nil, fmt.Errorf("offset %d: truncated immediate-to-reg/mem instruction", pointer)
+			}
+			signExtendBit := (byte1 >> 1) & 0b1
+			wideBit := byte1 & 0b1
+			byte2 := data[pointer+1]
+			mode := (byte2 >> 6) & 0b11
+			opField := (byte2 >> 3) & 0b111
+			rm := byte2 & 0b111
+
+			mnemonic, ok := verifyImmToRegMemMnemonics[opField]
+			if !ok {
+				return nil, fmt.Errorf("offset %d: unsupported arithmetic extension %b", pointer, opField)
+			}
+
+			var destText string
+			dataOffset := pointer + 2
+			if mode == 0b11 {
+				destText = decodeRegName(rm, wideBit == 1)
+			} else {
+				text, dispLen := decodeMemEquation(data, pointer+2, mode, rm)
+				destText = text
+				dataOffset = pointer + 2 + dispLen
+			}
+
+			dataLen := 1
+			var value int64
+			switch {
+			case wideBit == 1 && signExtendBit == 0:
+				value = int64(binary.LittleEndian.Uint16(data[dataOffset : dataOffset+2]))
+				dataLen = 2
+			case wideBit == 1:
+				// Sign-extend the byte to match the 16-bit operand, as the
+				// decoders do.
+				value = int64(int8(data[dataOffset]))
+			default:
+				value = int64(data[dataOffset])
+			}
+			pointer = dataOffset + dataLen
+
+			if mode == 0b11 {
+				lines = append(lines, line{start, fmt.Sprintf("%s %s, %d", mnemonic, destText, value)})
+			} else {
+				size := "byte"
+				if wideBit == 1 {
+					size = "word"
+				}
+				lines = append(lines, line{start, fmt.Sprintf("%s %s %s, %d", mnemonic, size, destText, value)})
+			}
+			continue
+		}
+
+		mnemonic, ok := verifyRegMemMnemonics[byte1>>2]
+		if !ok {
+			return nil, fmt.Errorf("offset %d: unknown opcode %b", pointer, byte1)
+		}
+		if pointer+2 > len(data) {
+			return nil, fmt.Errorf("offset %d: truncated reg/mem instruction", pointer)
+		}
+		destinationBit := (byte1 >> 1) & 0b1
+		wideBit := byte1 & 0b1
+		byte2 := data[pointer+1]
+		mode := (byte2 >> 6) & 0b11
+		reg := (byte2 >> 3) & 0b111
+		rm := byte2 & 0b111
+
+		regText := decodeRegName(reg, wideBit == 1)
+		var rmText string
+		if mode == 0b11 {
+			rmText = decodeRegName(rm, wideBit == 1)
+			pointer += 2
+		} else {
+			text, dispLen := decodeMemEquation(data, pointer+2, mode, rm)
+			rmText = text
+			pointer += 2 + dispLen
+		}
+
+		destText, srcText := rmText, regText
+		if destinationBit == 1 {
+			destText, srcText = regText, rmText
+		}
+		lines = append(lines, line{start, fmt.Sprintf("%s %s, %s", mnemonic, destText, srcText)})
+	}
+
+	result := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if labelTargets[l.Address] {
+			result = append(result, fmt.Sprintf("label_0x%x:", l.Address))
+		}
+		result = append(result, l.Text)
+	}
+	return result, nil
+}
+
+// VerifyRoundTrip checks Decode(Encode(Decode(binary))) == Decode(binary)
+// for the instruction subset Encode supports, returning a descriptive
+// error on the first mismatch.
+func VerifyRoundTrip(binary []byte) error {
+	decoded, err := decodeForVerify(binary)
+	if err != nil {
+		return fmt.Errorf("decoding input: %w", err)
+	}
+
+	reencoded, err := Encode(strings.Join(decoded, "\n"))
+	if err != nil {
+		return fmt.Errorf("re-encoding decoded output: %w", err)
+	}
+
+	redecoded, err := decodeForVerify(reencoded)
+	if err != nil {
+		return fmt.Errorf("decoding re-encoded output: %w", err)
+	}
+
+	if len(decoded) != len(redecoded) {
+		return fmt.Errorf("line count changed: %d vs %d", len(decoded), len(redecoded))
+	}
+	for i := range decoded {
+		if decoded[i] != redecoded[i] {
+			return fmt.Errorf("line %d: %q became %q", i, decoded[i], redecoded[i])
+		}
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) == 3 && os.Args[1] == "-verify" {
+		inputFile, err := os.Open(os.Args[2])
+		if err != nil {
+			panic(err)
+		}
+		binary, err := io.ReadAll(inputFile)
+		if err != nil {
+			panic(err)
+		}
+		if err := VerifyRoundTrip(binary); err != nil {
+			fmt.Printf("round-trip check failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Decode(Encode(Decode(binary))) == Decode(binary): ok")
+		return
+	}
+
+	if len(os.Args) != 2 {
+		fmt.Println("Usage: encoder <FILENAME.asm> (writes the encoded bytes to stdout)")
+		fmt.Println("       encoder -verify <FILENAME.bin> (checks the decode/encode round-trip)")
+		os.Exit(1)
+	}
+
+	inputFile, err := os.Open(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := io.ReadAll(inputFile)
+	if err != nil {
+		panic(err)
+	}
+
+	encoded, err := Encode(string(source))
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := os.Stdout.Write(encoded); err != nil {
+		panic(err)
+	}
+}
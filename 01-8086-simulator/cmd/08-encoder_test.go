@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Fixtures below are built by hand from the instruction forms this
+// package's decoders/encoder actually support (mov, add/sub/cmp
+// reg/mem and immediate forms, and the conditional jump/loop group) -
+// the same subset the course homework listings exercise. Run with:
+//
+//	go test 08-encoder.go 08-encoder_test.go
+func TestVerifyRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		bin  []byte
+	}{
+		{
+			name: "direct address operand (mov bx, [1000])",
+			bin:  []byte{0x8B, 0x1E, 0xE8, 0x03},
+		},
+		{
+			name: "register-direct immediate (add bx, 1000)",
+			bin:  []byte{0x81, 0xC3, 0xE8, 0x03},
+		},
+		{
+			name: "register-direct immediate, sign-extended byte (sub dx, 50)",
+			bin:  []byte{0x83, 0xEA, 0x32},
+		},
+		{
+			name: "[bp] with no displacement (mov si, [bp])",
+			bin:  []byte{0x8B, 0x76, 0x00},
+		},
+		{
+			name: "mixed program with a forward conditional jump",
+			bin: []byte{
+				0xB9, 0x0C, 0x00, // mov cx, 12
+				0x74, 0x03, // je label (+3 -> offset 8)
+				0x03, 0x5A, 0x04, // add bx, [bp + si + 4]
+				0x3D, 0xE8, 0x03, // cmp ax, 1000
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := VerifyRoundTrip(c.bin); err != nil {
+				t.Fatalf("Decode(Encode(Decode(binary))) != Decode(binary): %v", err)
+			}
+		})
+	}
+}
+
+// TestDecodeForVerify pins decodeForVerify's output against known-good
+// NASM text, so a bug that both the decode and re-decode pass in
+// VerifyRoundTrip agree on (and so never get caught by it) still fails
+// here.
+func TestDecodeForVerify(t *testing.T) {
+	cases := []struct {
+		name     string
+		bin      []byte
+		expected []string
+	}{
+		{
+			name:     "direct address operand",
+			bin:      []byte{0x8B, 0x1E, 0xE8, 0x03},
+			expected: []string{"mov bx, [1000]"},
+		},
+		{
+			name:     "register-direct immediate",
+			bin:      []byte{0x81, 0xC3, 0xE8, 0x03},
+			expected: []string{"add bx, 1000"},
+		},
+		{
+			name:     "[bp] with no displacement",
+			bin:      []byte{0x8B, 0x76, 0x00},
+			expected: []string{"mov si, [bp + 0]"},
+		},
+		{
+			name: "mixed program with a forward conditional jump",
+			bin: []byte{
+				0xB9, 0x0C, 0x00,
+				0x74, 0x03,
+				0x03, 0x5A, 0x04,
+				0x3D, 0xE8, 0x03,
+			},
+			expected: []string{
+				"mov cx, 12",
+				"je label_0x8",
+				"add bx, [bp + si + 4]",
+				"label_0x8:",
+				"cmp ax, 1000",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeForVerify(c.bin)
+			if err != nil {
+				t.Fatalf("decodeForVerify: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+				t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+			}
+		})
+	}
+}